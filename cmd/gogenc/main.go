@@ -0,0 +1,92 @@
+// Command gogenc is a client of gogen model service.
+//
+//	gogenc [-addr host:port] packages
+//	gogenc [-addr host:port] [-generator flag] model <package>
+//	gogenc [-addr host:port] [-generator flag] [-format dot] [-focus Type] [-depth 1] graph
+//	gogenc [-addr host:port] generate <package>
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/igadmg/gogen/net"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage of gogenc:\n")
+	fmt.Fprintf(os.Stderr, "\tgogenc [flags] packages|model <package>|graph|generate <package>\n")
+	fmt.Fprintf(os.Stderr, "Flags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:7077", "address of model service")
+	generator := flag.String("generator", "", "generator flag, may be omitted if service runs one generator")
+	format := flag.String("format", "dot", "graph format: dot, mermaid, plantuml")
+	focus := flag.String("focus", "", "graph only types related to this type")
+	depth := flag.Int("depth", 1, "how many relations away from -focus type are drawn")
+	flag.Usage = usage
+	flag.Parse()
+
+	log.SetFlags(0)
+	log.SetPrefix("gogenc: ")
+
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+	arg := func() string {
+		if len(args) < 2 {
+			usage()
+			os.Exit(2)
+		}
+		return args[1]
+	}
+
+	ctx := context.Background()
+	c := &net.Client{URL: "http://" + *addr}
+
+	var v any
+	var err error
+	failed := false
+	switch args[0] {
+	case "packages":
+		v, err = c.Packages(ctx)
+	case "model":
+		v, err = c.Model(ctx, *generator, arg())
+	case "graph":
+		var data []byte
+		data, err = c.Graph(ctx, *generator, *format, *focus, *depth)
+		if err == nil {
+			os.Stdout.Write(data)
+			return
+		}
+	case "generate":
+		var result net.GenerateResult
+		result, err = c.Generate(ctx, arg())
+		v = result
+		failed = len(result.Errors) > 0
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	e := json.NewEncoder(os.Stdout)
+	e.SetIndent("", "  ")
+	if err := e.Encode(v); err != nil {
+		log.Fatal(err)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
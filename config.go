@@ -0,0 +1,273 @@
+package gogen
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/igadmg/gogen/core"
+	"gopkg.in/yaml.v3"
+)
+
+const ConfigFileName = "gogen.yaml"
+
+// Config is a gogen.yaml project configuration. Config files are read from
+// module root down to package directory, nearer files override keys set by
+// module level ones. Options are command line flags, so they apply to whole
+// run and are taken from config of the directory gogen runs in only. Package
+// with options set in a nearer config is rejected.
+//
+//	generators: [ecs]        # generators enabled when none given in command line
+//	exclude: [internal/...]  # package directories excluded from generation
+//	options:                 # values of command line flags not given explicitly
+//	  no_store_dot: false
+//	settings:                # generator specific settings and flags
+//	  ecs:
+//	    layer: LayerGame
+type Config struct {
+	Generators []string                  `yaml:"generators"`
+	Exclude    []string                  `yaml:"exclude"`
+	Options    map[string]any            `yaml:"options"`
+	Settings   map[string]map[string]any `yaml:"settings"`
+
+	excludeDirs []string // exclude patterns resolved to absolute directories
+	optionsFile string   // nearest config file setting options, empty for none
+}
+
+func ReadConfig(fileName string) (*Config, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	if len(c.Options) > 0 {
+		c.optionsFile = fileName
+	}
+
+	dir := filepath.Dir(fileName)
+	for _, e := range c.Exclude {
+		c.excludeDirs = append(c.excludeDirs, filepath.Join(dir, filepath.FromSlash(e)))
+	}
+
+	return c, nil
+}
+
+// Merge returns c with keys set in nearer config n overridden.
+func (c *Config) Merge(n *Config) *Config {
+	r := &Config{
+		Generators:  c.Generators,
+		excludeDirs: slices.Concat(c.excludeDirs, n.excludeDirs),
+		optionsFile: c.optionsFile,
+		Options:     maps.Clone(c.Options),
+		Settings:    map[string]map[string]any{},
+	}
+
+	if n.Generators != nil {
+		r.Generators = n.Generators
+	}
+	if n.optionsFile != "" {
+		r.optionsFile = n.optionsFile
+	}
+
+	if r.Options == nil {
+		r.Options = map[string]any{}
+	}
+	maps.Copy(r.Options, n.Options)
+
+	for name, s := range c.Settings {
+		r.Settings[name] = maps.Clone(s)
+	}
+	for name, s := range n.Settings {
+		if r.Settings[name] == nil {
+			r.Settings[name] = map[string]any{}
+		}
+		maps.Copy(r.Settings[name], s)
+	}
+
+	return r
+}
+
+// Excludes reports whether dir is excluded from generation. Pattern ending
+// with /... excludes all directories below it too.
+func (c *Config) Excludes(dir string) bool {
+	for _, e := range c.excludeDirs {
+		if base, ok := strings.CutSuffix(e, string(filepath.Separator)+"..."); ok {
+			if dir == base || strings.HasPrefix(dir, base+string(filepath.Separator)) {
+				return true
+			}
+			continue
+		}
+
+		if ok, _ := filepath.Match(e, dir); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ApplyOptions sets flags from Options which were not given in command line.
+// Generator settings are applied to generator flags of the same name, like
+// setting layer of ecs generator sets -ecs.layer flag.
+func (c *Config) ApplyOptions(fg *flag.FlagSet) error {
+	set := map[string]bool{}
+	fg.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	errs := []error{}
+	for _, name := range slices.Sorted(maps.Keys(c.Options)) {
+		if set[name] {
+			continue
+		}
+
+		if err := fg.Set(name, optionValue(c.Options[name])); err != nil {
+			errs = append(errs, fmt.Errorf("option %s: %w", name, err))
+		}
+	}
+
+	for _, gname := range slices.Sorted(maps.Keys(c.Settings)) {
+		for _, key := range slices.Sorted(maps.Keys(c.Settings[gname])) {
+			name := gname + "." + key
+			if set[name] || fg.Lookup(name) == nil {
+				continue
+			}
+
+			if err := fg.Set(name, optionValue(c.Settings[gname][key])); err != nil {
+				errs = append(errs, fmt.Errorf("setting %s: %w", name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func optionValue(v any) string {
+	switch vv := v.(type) {
+	case []any:
+		values := make([]string, 0, len(vv))
+		for _, i := range vv {
+			values = append(values, fmt.Sprint(i))
+		}
+		return strings.Join(values, ",")
+	}
+
+	return fmt.Sprint(v)
+}
+
+// Configs resolves effective configuration of package directories.
+type Configs struct {
+	// Generators enabled in command line, nil if none was given there.
+	// Command line choice wins over generators set in config files.
+	Generators map[string]bool
+
+	mu          sync.Mutex
+	cache       map[string]*Config
+	optionsFile string // config file options were applied from
+}
+
+func NewConfigs() *Configs {
+	return &Configs{
+		cache: map[string]*Config{},
+	}
+}
+
+// ForDir returns config merged from module root down to dir.
+func (cs *Configs) ForDir(dir string) (*Config, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	return cs.forDir(filepath.Clean(dir))
+}
+
+func (cs *Configs) forDir(dir string) (*Config, error) {
+	if c, ok := cs.cache[dir]; ok {
+		return c, nil
+	}
+
+	c := &Config{}
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err != nil {
+		if parent := filepath.Dir(dir); parent != dir {
+			if c, err = cs.forDir(parent); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	fileName := filepath.Join(dir, ConfigFileName)
+	if _, err := os.Stat(fileName); err == nil {
+		n, err := ReadConfig(fileName)
+		if err != nil {
+			return nil, err
+		}
+		c = c.Merge(n)
+	}
+
+	cs.cache[dir] = c
+	return c, nil
+}
+
+// ApplyOptions applies options and settings of config for dir to fg.
+func (cs *Configs) ApplyOptions(dir string, fg *flag.FlagSet) error {
+	c, err := cs.ForDir(dir)
+	if err != nil {
+		return err
+	}
+
+	cs.optionsFile = c.optionsFile
+	return c.ApplyOptions(fg)
+}
+
+// Check returns error if config of pkg sets options other than ones applied
+// by ApplyOptions.
+func (cs *Configs) Check(pkg *core.Package) error {
+	c, err := cs.ForDir(pkg.Pkg.Dir)
+	if err != nil {
+		return err
+	}
+
+	if c.optionsFile != cs.optionsFile {
+		return fmt.Errorf("%s: options apply to whole run, set them in config of the directory gogen runs in", c.optionsFile)
+	}
+
+	return nil
+}
+
+// Enabled reports whether generator with flag name runs for pkg.
+func (cs *Configs) Enabled(pkg *core.Package, name string) bool {
+	c, err := cs.ForDir(pkg.Pkg.Dir)
+	if err != nil {
+		return false
+	}
+
+	if c.Excludes(filepath.Clean(pkg.Pkg.Dir)) {
+		return false
+	}
+
+	if cs.Generators != nil {
+		return cs.Generators[name]
+	}
+
+	return slices.Contains(c.Generators, name)
+}
+
+// Settings returns settings of generator with flag name for pkg.
+func (cs *Configs) Settings(pkg *core.Package, name string) map[string]any {
+	c, err := cs.ForDir(pkg.Pkg.Dir)
+	if err != nil {
+		return nil
+	}
+
+	return c.Settings[name]
+}
@@ -0,0 +1,84 @@
+package gogen
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"github.com/igadmg/gogen/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestConfigsForDir(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		ConfigFileName: `
+generators: [ecs]
+exclude: [internal/...]
+options:
+  no_store_dot: false
+settings:
+  ecs:
+    layer: LayerGame
+    verbose: true
+`,
+		"game/" + ConfigFileName: `
+generators: [ecs, gfx]
+settings:
+  ecs:
+    layer: LayerWorld
+`,
+	})
+	pkgDir := filepath.Join(root, "game", "world")
+
+	cs := NewConfigs()
+	c, err := cs.ForDir(pkgDir)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"ecs", "gfx"}, c.Generators)
+	assert.Equal(t, map[string]any{"layer": "LayerWorld", "verbose": true}, c.Settings["ecs"])
+	assert.Equal(t, false, c.Options["no_store_dot"])
+	assert.True(t, c.Excludes(filepath.Join(root, "internal", "db")))
+	assert.False(t, c.Excludes(pkgDir))
+}
+
+func TestApplyOptionsGeneratorFlags(t *testing.T) {
+	fg := flag.NewFlagSet("gogen", flag.ContinueOnError)
+	layer := fg.String("ecs.layer", "", "")
+	verbose := fg.Bool("ecs.verbose", false, "")
+	require.NoError(t, fg.Parse([]string{"-ecs.verbose=false"}))
+
+	c := &Config{
+		Settings: map[string]map[string]any{
+			"ecs": {"layer": "LayerGame", "verbose": true, "unknown": 1},
+		},
+	}
+	require.NoError(t, c.ApplyOptions(fg))
+
+	assert.Equal(t, "LayerGame", *layer)
+	assert.False(t, *verbose, "command line wins over settings")
+}
+
+func TestConfigsCheck(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		ConfigFileName:             "options:\n  no_store_dot: true\n",
+		"world/" + ConfigFileName:  "settings:\n  ecs:\n    layer: LayerWorld\n",
+		"render/" + ConfigFileName: "options:\n  diagram: svg\n",
+	})
+
+	fg := flag.NewFlagSet("gogen", flag.ContinueOnError)
+	noStoreDot := fg.Bool("no_store_dot", false, "")
+	fg.String("diagram", "", "")
+
+	cs := NewConfigs()
+	require.NoError(t, cs.ApplyOptions(root, fg))
+	assert.True(t, *noStoreDot)
+
+	pkg := func(dir string) *core.Package {
+		return &core.Package{Pkg: &packages.Package{Dir: filepath.Join(root, dir)}}
+	}
+	assert.NoError(t, cs.Check(pkg(".")))
+	assert.NoError(t, cs.Check(pkg("world")))
+	assert.Error(t, cs.Check(pkg("render")), "options of nearer config are rejected")
+}
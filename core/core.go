@@ -2,11 +2,10 @@ package core
 
 import (
 	"go/ast"
-	"time"
 )
 
 type File struct {
-	Pkg     *Package  // Package to which this file belongs.
-	File    *ast.File // Parsed AST.
-	ModTime time.Time
+	Pkg  *Package  // Package to which this file belongs.
+	File *ast.File // Parsed AST.
+	Hash string    // Content hash of source file.
 }
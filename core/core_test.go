@@ -1,9 +1,20 @@
 package core
 
 import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
 	"testing"
+	"testing/fstest"
 
+	"deedles.dev/xiter"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/tools/go/packages"
 )
 
@@ -63,3 +74,563 @@ func TestPkgAboveTag(t *testing.T) {
 	assert.True(t, !p3.Above(&p1))
 	assert.True(t, !p1.Above(&p3))
 }
+
+func TestPackageInputHash(t *testing.T) {
+	p1 := Package{Hash: "a"}
+	p2 := Package{Hash: "b", ImportedPkgs: map[string]*Package{"p1": &p1}}
+	p3 := Package{Hash: "b"}
+
+	assert.NotEqual(t, p2.InputHash(), p3.InputHash())
+	assert.Equal(t, p2.InputHash(), (&Package{Hash: "b", ImportedPkgs: map[string]*Package{"p1": {Hash: "a"}}}).InputHash())
+	assert.NotEqual(t, HashStrings("ab", "c"), HashStrings("a", "bc"))
+}
+
+func TestDiagnostics(t *testing.T) {
+	d := Diagnostics{}
+	d.Warnf(nil, "type %s not found", "Vec")
+	assert.False(t, d.HasErrors())
+	assert.Equal(t, "warning: type Vec not found", d[0].String())
+
+	d.Errorf(nil, "bad tag")
+	assert.True(t, d.HasErrors())
+}
+
+type testGenerator struct {
+	GeneratorBaseT
+}
+
+func newTestGenerator() *testGenerator {
+	g := &testGenerator{
+		GeneratorBaseT: MakeGeneratorB("test"),
+	}
+	g.G = g
+	return g
+}
+
+func (g *testGenerator) Generate(pkg *Package) (bytes.Buffer, Diagnostics, error) {
+	return bytes.Buffer{}, g.TakeDiagnostics(), nil
+}
+
+type testImporter map[string]*types.Package
+
+func (i testImporter) Import(path string) (*types.Package, error) {
+	if p, ok := i[path]; ok {
+		return p, nil
+	}
+	return importer.Default().Import(path)
+}
+
+// loadTestPackage type checks src and inspects its types and funcs with g.
+func loadTestPackage(t *testing.T, g Generator, imp testImporter, path, src string) *Package {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path+"/src.go", src, parser.ParseComments)
+	require.NoError(t, err)
+
+	info := &types.Info{
+		Types: map[ast.Expr]types.TypeAndValue{},
+		Defs:  map[*ast.Ident]types.Object{},
+		Uses:  map[*ast.Ident]types.Object{},
+	}
+	tpkg, err := (&types.Config{Importer: imp}).Check(path, fset, []*ast.File{file}, info)
+	require.NoError(t, err)
+	imp[path] = tpkg
+
+	pkg := NewPackage(&packages.Package{
+		PkgPath:   path,
+		Name:      file.Name.Name,
+		Fset:      fset,
+		Syntax:    []*ast.File{file},
+		Types:     tpkg,
+		TypesInfo: info,
+	})
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					g.NewType(pkg, nil, spec)
+				case *ast.ImportSpec:
+					pkg.AddImport(spec)
+				}
+			}
+		case *ast.FuncDecl:
+			g.NewFunc(pkg, nil, decl)
+		}
+	}
+
+	return pkg
+}
+
+// prepareTestPackages inspects srcs as packages example.com/<name>, in
+// order, and prepares test generator for the last one, which is returned.
+func prepareTestPackages(t *testing.T, srcs ...string) (*testGenerator, *Package) {
+	t.Helper()
+
+	g := newTestGenerator()
+	imp := testImporter{}
+	for _, src := range srcs {
+		file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.PackageClauseOnly)
+		require.NoError(t, err)
+
+		g.Pkg = loadTestPackage(t, g, imp, "example.com/"+file.Name.Name, src)
+	}
+	g.Prepare()
+
+	return g, g.Pkg
+}
+
+// testType returns type of g with name.
+func testType(t *testing.T, g Generator, name string) TypeI {
+	t.Helper()
+
+	tt, ok := g.GetType(name)
+	require.True(t, ok, "type %s", name)
+	return tt
+}
+
+// testField returns field of tt with name.
+func testField(t *testing.T, tt TypeI, name string) FieldI {
+	t.Helper()
+
+	for f := range tt.FieldsSeq() {
+		if f.GetName() == name {
+			return f
+		}
+	}
+	require.Fail(t, "field not found", "%s.%s", tt.GetName(), name)
+	return nil
+}
+
+// testFunc returns function or method of tt with name.
+func testFunc(t *testing.T, tt TypeI, name string) *Func {
+	t.Helper()
+
+	for f := range EnumFuncsSeq(tt.FuncsSeq()) {
+		if f.Name == name {
+			return f
+		}
+	}
+	require.Fail(t, "func not found", "%s.%s", tt.GetName(), name)
+	return nil
+}
+
+// testTypeDto returns exported type of model with name.
+func testTypeDto(t *testing.T, model PackageDto, name string) TypeDto {
+	t.Helper()
+
+	for _, tt := range model.Types {
+		if tt.Name == name {
+			return tt
+		}
+	}
+	require.Fail(t, "type not found", name)
+	return TypeDto{}
+}
+
+// testJsonItem returns object of JSON array items with name.
+func testJsonItem(t *testing.T, items any, name string) map[string]any {
+	t.Helper()
+
+	for _, item := range items.([]any) {
+		if obj := item.(map[string]any); obj["name"] == name {
+			return obj
+		}
+	}
+	require.Fail(t, "item not found", name)
+	return nil
+}
+
+func TestFieldTypeResolve(t *testing.T) {
+	g, _ := prepareTestPackages(t, `package math
+type Vec struct { X, Y float32 }
+`, `package body
+import m "example.com/math"
+type Body struct {
+	Pos  m.Vec
+	Ptr  *m.Vec
+	List []m.Vec
+}
+`)
+
+	vec := testType(t, g, "math.Vec")
+	body := testType(t, g, "body.Body")
+
+	for f := range body.FieldsSeq() {
+		assert.Equal(t, vec, f.GetType(), f.GetName())
+	}
+
+	g.Pkg = body.GetPackage()
+	assert.Equal(t, "math.", g.TypeImportName(vec))
+	assert.Equal(t, "*math.Vec", g.FieldTypeName(testField(t, body, "Ptr")))
+	assert.Equal(t, "[]math.Vec", g.FieldTypeName(testField(t, body, "List")))
+}
+
+func TestGenericTypes(t *testing.T) {
+	g, _ := prepareTestPackages(t, `package pool
+type Vec[T any] struct { X, Y T }
+type Pool[K comparable, T any] struct {
+	Items []T
+	Pos   Vec[float32]
+	Ptr   *Vec[T]
+}
+`)
+
+	vec := testType(t, g, "pool.Vec")
+	pool := testType(t, g, "pool.Pool")
+
+	assert.Equal(t, "[K comparable, T any]", pool.(*Type).DeclTypeParams())
+	assert.Equal(t, "Pool[K, T]", g.LocalTypeName(pool))
+
+	pos := testField(t, pool, "Pos")
+	assert.Equal(t, vec, pos.GetType())
+	assert.Equal(t, "Vec[float32]", g.FieldTypeName(pos))
+	assert.Equal(t, "*Vec[T]", g.FieldTypeName(testField(t, pool, "Ptr")))
+	assert.Equal(t, "[]T", g.FieldTypeName(testField(t, pool, "Items")))
+	assert.Equal(t, "", g.TypeImportName(testField(t, pool, "Items").GetType()))
+
+	var f Field
+	f.decltype = "[]*Vec[T]"
+	f.Type = vec
+	assert.Equal(t, "[]*Vec[T]", g.FieldTypeName(&f), "without type info")
+}
+
+func TestTypeKinds(t *testing.T) {
+	g, _ := prepareTestPackages(t, `package kinds
+type Drawer interface {
+	Draw(layer Layer)
+}
+type Layer int
+type Handler func(name string)
+type Alias = Layer
+`)
+
+	kinds := map[string]TypeKind{
+		"Drawer":  KindInterface,
+		"Layer":   KindNamed,
+		"Handler": KindFunc,
+		"Alias":   KindAlias,
+	}
+	for name, kind := range kinds {
+		assert.Equal(t, kind, testType(t, g, name).GetKind(), name)
+	}
+
+	assert.True(t, testType(t, g, "Drawer").HasFunction("Draw"))
+	assert.Equal(t, "int", testType(t, g, "Layer").(*Type).Underlying)
+}
+
+func TestMultiNameAndInlineFields(t *testing.T) {
+	g, _ := prepareTestPackages(t, `package shape
+type Rect struct {
+	X, Y, W, H float32
+	Style struct {
+		Color, Border int
+	}
+}
+`)
+
+	rect := testType(t, g, "Rect")
+
+	names := slices.Collect(xiter.Map(rect.FieldsSeq(), FieldI.GetName))
+	assert.Equal(t, []string{"X", "Y", "W", "H", "Style"}, names)
+
+	style := testField(t, rect, "Style")
+	require.NotNil(t, style.GetType())
+	styleNames := slices.Collect(xiter.Map(style.GetType().FieldsSeq(), FieldI.GetName))
+	assert.Equal(t, []string{"Color", "Border"}, styleNames)
+	assert.Equal(t, "struct{Color, Border int}", g.FieldTypeName(style))
+}
+
+func TestFuncResults(t *testing.T) {
+	g, _ := prepareTestPackages(t, `package res
+type World struct{}
+func (w *World) Count() int { return 0 }
+func (w *World) Load(name string) (int, error) { return 0, nil }
+func (w *World) Size() (w0, h0 int, err error) { return }
+func (w *World) Check() (error, error) { return nil, nil }
+func (w *World) Skip() (_ int, r0 int, _ error) { return }
+func (w *World) Apply(r0 int) int { return r0 }
+func (r0 *World) Self() (int, error) { return 0, nil }
+func (w *World) Fail(err error) error { return err }
+`)
+
+	world := testType(t, g, "World")
+	assert.Equal(t, "int", testFunc(t, world, "Count").DeclResults())
+	assert.Equal(t, "r0", testFunc(t, world, "Count").CallResults())
+	assert.Equal(t, "(int, error)", testFunc(t, world, "Load").DeclResults())
+	assert.Equal(t, "r0, err", testFunc(t, world, "Load").CallResults())
+	assert.True(t, testFunc(t, world, "Load").ReturnsError())
+	assert.Equal(t, "(w0 int, h0 int, err error)", testFunc(t, world, "Size").DeclResults())
+	assert.Equal(t, "w0, h0, err", testFunc(t, world, "Size").CallResults())
+	assert.Equal(t, "r0, err", testFunc(t, world, "Check").CallResults())
+	assert.Equal(t, "r0_, r0, err", testFunc(t, world, "Skip").CallResults())
+	assert.Equal(t, "r0_", testFunc(t, world, "Apply").CallResults(), "argument name")
+	assert.Equal(t, "r0_, err", testFunc(t, world, "Self").CallResults(), "receiver name")
+	assert.Equal(t, "err_", testFunc(t, world, "Fail").CallResults(), "argument name")
+}
+
+func TestFuncParameters(t *testing.T) {
+	g, _ := prepareTestPackages(t, `package params
+type World struct{}
+func (w *World) Move(x, y float32) {}
+func (w *World) Draw(int, string) {}
+func (w *World) Log(format string, args ...any) {}
+func (w *World) Skip(_ int, name string) {}
+func (w *World) Shadow(_ int, p0 string) {}
+func (w *World) Result(int) (p0 int) { return }
+func (p0 *World) Recv(int) {}
+func (w *World) Each(fn func(int) bool, m map[string][]*World, ch <-chan struct{}) {}
+`)
+
+	world := testType(t, g, "World")
+	assert.Equal(t, "x float32, y float32", testFunc(t, world, "Move").DeclArguments())
+	assert.Equal(t, "x, y", testFunc(t, world, "Move").CallArguments())
+	assert.Equal(t, "p0 int, p1 string", testFunc(t, world, "Draw").DeclArguments())
+	assert.Equal(t, "p0, p1", testFunc(t, world, "Draw").CallArguments())
+	assert.Equal(t, "format string, args ...any", testFunc(t, world, "Log").DeclArguments())
+	assert.Equal(t, "format, args...", testFunc(t, world, "Log").CallArguments())
+	assert.Equal(t, "p0, name", testFunc(t, world, "Skip").CallArguments())
+	assert.Equal(t, "p0_ int, p0 string", testFunc(t, world, "Shadow").DeclArguments())
+	assert.Equal(t, "p0_ int", testFunc(t, world, "Result").DeclArguments(), "result name")
+	assert.Equal(t, "(p0 int)", testFunc(t, world, "Result").DeclResults())
+	assert.Equal(t, "p0_ int", testFunc(t, world, "Recv").DeclArguments(), "receiver name")
+	assert.Equal(t, "fn func(int) bool, m map[string][]*World, ch <-chan struct{}", testFunc(t, world, "Each").DeclArguments())
+}
+
+func TestFuncReceivers(t *testing.T) {
+	g, _ := prepareTestPackages(t, `package recv
+type Pool[T any] struct{ Items []T }
+func (p *Pool[T]) Add(item T) {}
+func (p Pool[T]) Len() int { return len(p.Items) }
+func New() *Pool[int] { return nil }
+`)
+
+	pool := testType(t, g, "Pool")
+	assert.True(t, pool.HasFunction("Add"))
+	assert.True(t, pool.HasFunction("Len"))
+
+	add := testFunc(t, pool, "Add")
+	require.NotNil(t, add.Receiver)
+	assert.True(t, add.Receiver.Pointer)
+	assert.Equal(t, []string{"T"}, add.Receiver.TypeParams)
+	assert.Equal(t, pool, add.Receiver.Type)
+	assert.Equal(t, "(p *Pool[T])", add.Receiver.Decl())
+
+	assert.False(t, testFunc(t, pool, "Len").Receiver.Pointer)
+}
+
+func TestSameNamePackages(t *testing.T) {
+	g := newTestGenerator()
+	imp := testImporter{}
+
+	loadTestPackage(t, g, imp, "example.com/a/model", `package model
+type Player struct{}
+func (p *Player) Move() {}
+`)
+	loadTestPackage(t, g, imp, "example.com/b/model", `package model
+type Player struct{}
+func (p *Player) Jump() {}
+`)
+	g.Prepare()
+
+	a := testType(t, g, "example.com/a/model.Player")
+	b := testType(t, g, "example.com/b/model.Player")
+	assert.True(t, a.HasFunction("Move"))
+	assert.False(t, a.HasFunction("Jump"))
+	assert.True(t, b.HasFunction("Jump"))
+	assert.False(t, b.HasFunction("Move"))
+
+	_, ok := g.GetType("model.Player")
+	assert.False(t, ok, "package name is ambiguous")
+
+	dg, err := MarshalDot(g.Graph())
+	require.NoError(t, err)
+	assert.Contains(t, string(dg), `"example.com/a/model.Player"`)
+	assert.Contains(t, string(dg), `"example.com/b/model.Player"`)
+
+	mmd, err := MarshalMermaid(g.Graph())
+	require.NoError(t, err)
+	assert.Contains(t, string(mmd), `class example_com_a_model_Player["model.Player"]`)
+	assert.Contains(t, string(mmd), `class example_com_b_model_Player["model.Player"]`)
+}
+
+func TestTemplateGenerator(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/0_helpers.tmpl": {Data: []byte(`{{define "method"}}func ({{.Receiver.Name}} {{.Receiver.DeclType}}) {{.Name}}Logged({{declArguments .}}) {{declResults .}}{{end}}`)},
+		"templates/1_types.tmpl": {Data: []byte(`package {{.Pkg.Name}}
+{{range .Types}}{{$t := .}}{{range fields .}}{{if hasTag . "log"}}
+// {{localTypeName $t}}.{{.Name}} logs as {{tag . "log"}}
+{{end}}{{end}}{{range funcs .}}{{template "method" .}}
+{{end}}{{end}}`)},
+	}
+
+	g, err := NewTemplateGenerator("tmpl", fsys, []string{"templates/*.tmpl"}, "tmpl")
+	require.NoError(t, err)
+	defer func(tags []string) { Tags = tags }(Tags)
+	Tags = append(Tags, "tmpl")
+
+	pkg := loadTestPackage(t, g, testImporter{}, "example.com/tmpl", `package tmpl
+type Player struct {
+	Name string `+"`tmpl:\"log: name\"`"+`
+	Score int
+}
+func (p *Player) Rename(name string) error { return nil }
+func NewPlayer(name string) *Player { return nil }
+`)
+
+	code, _, err := g.Generate(pkg)
+	require.NoError(t, err)
+	assert.Contains(t, code.String(), "// Player.Name logs as name")
+	assert.Contains(t, code.String(), "func (p *Player) RenameLogged(name string) error")
+	assert.NotContains(t, code.String(), "Score")
+
+	funcs := []string{}
+	for _, f := range g.Data(pkg).Funcs {
+		funcs = append(funcs, f.GetName())
+	}
+	slices.Sort(funcs)
+	assert.Equal(t, []string{"NewPlayer", "Rename"}, funcs)
+}
+
+func TestModelYaml(t *testing.T) {
+	g, pkg := prepareTestPackages(t, `package model
+type Base struct{ ID int }
+type Player struct {
+	Base
+	Name string
+	Pos  struct{ X, Y int }
+}
+func (p *Player) Move(dx, dy int) {}
+func NewPlayer(name string) *Player { return nil }
+`)
+
+	model := g.Model(pkg)
+	require.Len(t, model.Types, 2)
+	base, player := testTypeDto(t, model, "Base"), testTypeDto(t, model, "Player")
+	assert.Equal(t, []string{"model.Player"}, base.Subclasses)
+	require.Len(t, player.Bases, 1)
+	assert.Equal(t, "model.Base", player.Bases[0].Type)
+	assert.Equal(t, "src.go:3:6", player.Position)
+	require.Len(t, player.Funcs, 1)
+	assert.Equal(t, "(p *Player)", player.Funcs[0].Receiver)
+	require.Len(t, model.Funcs, 1)
+	assert.Equal(t, "NewPlayer", model.Funcs[0].Name)
+
+	data, err := g.Yaml(pkg)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "subclasses:\n        - model.Player")
+	assert.Contains(t, string(data), "inline:")
+}
+
+func TestModelJson(t *testing.T) {
+	g, pkg := prepareTestPackages(t, `package model
+type Base struct{}
+type Player struct {
+	Base
+	Name string
+}
+`)
+
+	data, err := g.Json(pkg)
+	require.NoError(t, err)
+
+	var model map[string]any
+	require.NoError(t, json.Unmarshal(data, &model))
+
+	var schema struct {
+		Required []string `json:"required"`
+		Defs     map[string]struct {
+			Properties map[string]struct {
+				Enum []string `json:"enum"`
+			} `json:"properties"`
+		} `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(ModelSchema, &schema))
+	for _, key := range schema.Required {
+		assert.Contains(t, model, key)
+	}
+
+	require.Len(t, model["types"], 2)
+	player := testJsonItem(t, model["types"], "Player")
+	assert.Contains(t, schema.Defs["type"].Properties["kind"].Enum, player["kind"])
+	assert.Equal(t, "model.Base", testJsonItem(t, player["bases"], "")["type"])
+	assert.Equal(t, []any{"model.Player"}, testJsonItem(t, model["types"], "Base")["subclasses"])
+}
+
+func TestTypeGraph(t *testing.T) {
+	g, _ := prepareTestPackages(t, `package graph
+type Base struct{}
+type Pos struct{ X, Y int }
+type Player struct {
+	Base
+	Pos  Pos
+	Name string
+}
+`)
+
+	tg := g.Graph().(*TypeGraph)
+	assert.Equal(t, 3, tg.Nodes().Len())
+
+	base, pos, player := testType(t, g, "Base"), testType(t, g, "Pos"), testType(t, g, "Player")
+	assert.True(t, tg.HasRelation(player, base, EdgeBase))
+	assert.True(t, tg.HasRelation(player, pos, EdgeField))
+	assert.False(t, tg.HasRelation(base, player, EdgeSubclass), "subclass is related back by base")
+	assert.Equal(t, 2, tg.Edges().Len())
+}
+
+func TestDiagrams(t *testing.T) {
+	g, _ := prepareTestPackages(t, `package diag
+type Base struct{}
+type Pos struct{ X, Y int }
+type Player struct {
+	Base
+	Pos   Pos
+	Items []Pos
+}
+`)
+
+	mmd, err := MarshalMermaid(g.Graph())
+	require.NoError(t, err)
+	assert.Contains(t, string(mmd), `class example_com_diag_Player["diag.Player"] {`)
+	assert.Contains(t, string(mmd), "+[]Pos Items")
+	assert.Contains(t, string(mmd), "example_com_diag_Base <|-- example_com_diag_Player")
+	assert.Contains(t, string(mmd), "example_com_diag_Player --> example_com_diag_Pos : Pos")
+
+	puml, err := MarshalPlantUML(g.Graph())
+	require.NoError(t, err)
+	assert.Contains(t, string(puml), `class "diag.Player" as example_com_diag_Player {`)
+	assert.Contains(t, string(puml), "  Items : []Pos")
+	assert.Contains(t, string(puml), "example_com_diag_Base <|-- example_com_diag_Player")
+}
+
+func TestDotFocus(t *testing.T) {
+	g, _ := prepareTestPackages(t, `package focus
+type World struct{ Player Player }
+type Player struct {
+	Pos  Pos
+	Name string
+}
+type Pos struct{ X, Y int }
+type Other struct{}
+`)
+
+	tg := g.Graph().(*TypeGraph)
+	player, ok := tg.Find("Player")
+	require.True(t, ok)
+
+	fg := tg.Focus(player.Type, 1)
+	assert.Equal(t, 3, fg.Nodes().Len())
+	_, ok = fg.Find("focus.Other")
+	assert.False(t, ok)
+	assert.Equal(t, 1, tg.Focus(player.Type, 0).Nodes().Len())
+
+	data, err := MarshalDot(fg)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "subgraph cluster_example_com_focus {")
+	assert.Contains(t, string(data), "<B>focus.Player</B>")
+	assert.Contains(t, string(data), "Name string")
+	assert.Contains(t, string(data), "label=Pos")
+}
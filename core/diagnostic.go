@@ -0,0 +1,78 @@
+package core
+
+import (
+	"fmt"
+	"go/token"
+)
+
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// Diagnostic is a message reported by generator about source code.
+type Diagnostic struct {
+	Severity Severity
+	Pos      token.Position // Position of offending type, field or func.
+	Message  string
+}
+
+func MakeDiagnostic(severity Severity, t TokenI, format string, args ...any) Diagnostic {
+	d := Diagnostic{
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if t != nil {
+		d.Pos = t.GetPosition()
+	}
+	return d
+}
+
+// String formats diagnostic as file:line:col: severity: message.
+func (d Diagnostic) String() string {
+	if d.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s: %s", d.Pos, d.Severity, d.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+}
+
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) HasErrors() bool {
+	for _, i := range d {
+		if i.Severity >= SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *Diagnostics) Errorf(t TokenI, format string, args ...any) {
+	*d = append(*d, MakeDiagnostic(SeverityError, t, format, args...))
+}
+
+func (d *Diagnostics) Warnf(t TokenI, format string, args ...any) {
+	*d = append(*d, MakeDiagnostic(SeverityWarning, t, format, args...))
+}
+
+func (d *Diagnostics) Infof(t TokenI, format string, args ...any) {
+	*d = append(*d, MakeDiagnostic(SeverityInfo, t, format, args...))
+}
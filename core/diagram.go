@@ -0,0 +1,218 @@
+package core
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/encoding/dot"
+)
+
+// diagramLine is a relation drawn in diagram. Lines of graphs not built by
+// GeneratorBaseT are drawn as field relations without name.
+type diagramLine struct {
+	from, to graph.Node
+	kind     EdgeKind
+	name     string
+}
+
+// diagramNodes returns nodes of g sorted by id.
+func diagramNodes(g graph.Graph) []graph.Node {
+	nodes := graph.NodesOf(g.Nodes())
+	slices.SortFunc(nodes, func(a, b graph.Node) int {
+		return cmp.Compare(a.ID(), b.ID())
+	})
+	return nodes
+}
+
+// diagramLines returns relations of g in node order.
+func diagramLines(g graph.Graph) []diagramLine {
+	lines := []diagramLine{}
+	for _, from := range diagramNodes(g) {
+		to := graph.NodesOf(g.From(from.ID()))
+		slices.SortFunc(to, func(a, b graph.Node) int {
+			return cmp.Compare(a.ID(), b.ID())
+		})
+
+		for _, t := range to {
+			var ls []graph.Line
+			if mg, ok := g.(graph.Multigraph); ok {
+				ls = graph.LinesOf(mg.Lines(from.ID(), t.ID()))
+			}
+			if len(ls) == 0 {
+				lines = append(lines, diagramLine{from: from, to: t, kind: EdgeField})
+				continue
+			}
+
+			slices.SortFunc(ls, func(a, b graph.Line) int {
+				return cmp.Compare(a.ID(), b.ID())
+			})
+			for _, l := range ls {
+				dl := diagramLine{from: from, to: t, kind: EdgeField}
+				if tl, ok := l.(TypeLine); ok {
+					dl.kind = tl.Kind
+					dl.name = tl.Name
+				}
+				lines = append(lines, dl)
+			}
+		}
+	}
+
+	return lines
+}
+
+// diagramLabel returns type full name of node or its DOT id.
+func diagramLabel(n graph.Node) string {
+	switch nn := n.(type) {
+	case *TypeNode:
+		return nn.Type.GetFullName()
+	case dot.Node:
+		return nn.DOTID()
+	}
+
+	return fmt.Sprint(n.ID())
+}
+
+// diagramID returns DOTID of node usable as identifier.
+func diagramID(n graph.Node) string {
+	id := fmt.Sprint(n.ID())
+	if dn, ok := n.(dot.Node); ok {
+		id = dn.DOTID()
+	}
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, id)
+}
+
+// diagramType returns type drawn by node, nil for nodes of other graphs.
+func diagramType(n graph.Node) TypeI {
+	if tn, ok := n.(*TypeNode); ok {
+		return tn.Type
+	}
+	return nil
+}
+
+// isEmptySeq reports whether seq yields nothing.
+func isEmptySeq[T any](seq iter.Seq[T]) bool {
+	for range seq {
+		return false
+	}
+	return true
+}
+
+func fieldDeclType(f FieldI) string {
+	if d := f.DeclType(); d != "" {
+		return d
+	}
+	return f.GetTypeName()
+}
+
+// MarshalDot returns g in DOT format.
+func MarshalDot(g graph.Graph) ([]byte, error) {
+	if mg, ok := g.(graph.Multigraph); ok {
+		return dot.MarshalMulti(mg, "", "", "  ")
+	}
+
+	return dot.Marshal(g, "", "", "  ")
+}
+
+// MarshalMermaid returns g as Mermaid classDiagram.
+func MarshalMermaid(g graph.Graph) ([]byte, error) {
+	members := strings.NewReplacer("[]", "[]", "[", "~", "]", "~", "{", "(", "}", ")")
+
+	var b bytes.Buffer
+	b.WriteString("classDiagram\n")
+	for _, n := range diagramNodes(g) {
+		fmt.Fprintf(&b, "  class %s[\"%s\"]", diagramID(n), diagramLabel(n))
+
+		t := diagramType(n)
+		if t == nil || (t.GetKind() != KindInterface && isEmptySeq(t.FieldsSeq())) {
+			b.WriteString("\n")
+			continue
+		}
+
+		b.WriteString(" {\n")
+		if t.GetKind() == KindInterface {
+			b.WriteString("    <<interface>>\n")
+		}
+		for f := range t.FieldsSeq() {
+			fmt.Fprintf(&b, "    +%s %s\n", members.Replace(fieldDeclType(f)), f.GetName())
+		}
+		b.WriteString("  }\n")
+	}
+
+	for _, l := range diagramLines(g) {
+		from, to := diagramID(l.from), diagramID(l.to)
+		switch l.kind {
+		case EdgeBase:
+			fmt.Fprintf(&b, "  %s <|-- %s\n", to, from)
+		case EdgeExtends:
+			fmt.Fprintf(&b, "  %s <|.. %s\n", to, from)
+		case EdgeSubclass:
+			fmt.Fprintf(&b, "  %s <|-- %s\n", from, to)
+		default:
+			if l.name != "" {
+				fmt.Fprintf(&b, "  %s --> %s : %s\n", from, to, l.name)
+			} else {
+				fmt.Fprintf(&b, "  %s --> %s\n", from, to)
+			}
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+// MarshalPlantUML returns g as PlantUML class diagram.
+func MarshalPlantUML(g graph.Graph) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("@startuml\n")
+	for _, n := range diagramNodes(g) {
+		keyword := "class"
+		t := diagramType(n)
+		if t != nil && t.GetKind() == KindInterface {
+			keyword = "interface"
+		}
+		fmt.Fprintf(&b, "%s \"%s\" as %s", keyword, diagramLabel(n), diagramID(n))
+
+		if t == nil {
+			b.WriteString("\n")
+			continue
+		}
+
+		b.WriteString(" {\n")
+		for f := range t.FieldsSeq() {
+			fmt.Fprintf(&b, "  %s : %s\n", f.GetName(), fieldDeclType(f))
+		}
+		b.WriteString("}\n")
+	}
+
+	for _, l := range diagramLines(g) {
+		from, to := diagramID(l.from), diagramID(l.to)
+		switch l.kind {
+		case EdgeBase:
+			fmt.Fprintf(&b, "%s <|-- %s\n", to, from)
+		case EdgeExtends:
+			fmt.Fprintf(&b, "%s <|.. %s\n", to, from)
+		case EdgeSubclass:
+			fmt.Fprintf(&b, "%s <|-- %s\n", from, to)
+		default:
+			if l.name != "" {
+				fmt.Fprintf(&b, "%s --> %s : %s\n", from, to, l.name)
+			} else {
+				fmt.Fprintf(&b, "%s --> %s\n", from, to)
+			}
+		}
+	}
+	b.WriteString("@enduml\n")
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,238 @@
+package core
+
+import (
+	"fmt"
+	"html"
+	"maps"
+	"slices"
+	"strings"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/encoding"
+	"gonum.org/v1/gonum/graph/encoding/dot"
+	"gonum.org/v1/gonum/graph/multi"
+)
+
+var _ dot.MultiStructurer = (*TypeGraph)(nil)
+var _ dot.Attributers = (*TypeGraph)(nil)
+var _ encoding.Attributer = (*TypeNode)(nil)
+var _ encoding.Attributer = TypeLine{}
+
+func (g *TypeGraph) DOTAttributers() (graph, node, edge encoding.Attributer) {
+	return attributes{{Key: "rankdir", Value: "BT"}},
+		attributes{{Key: "shape", Value: "plain"}, {Key: "fontname", Value: "Helvetica"}},
+		attributes{{Key: "fontname", Value: "Helvetica"}, {Key: "fontsize", Value: "10"}}
+}
+
+// Structure returns a cluster for every package types come from.
+func (g *TypeGraph) Structure() []dot.Multigraph {
+	clusters := map[*Package]*packageCluster{}
+	for _, n := range diagramNodes(g) {
+		tn, ok := n.(*TypeNode)
+		if !ok {
+			continue
+		}
+
+		pkg := tn.Type.GetPackage()
+		c, ok := clusters[pkg]
+		if !ok {
+			c = &packageCluster{
+				DirectedGraph: multi.NewDirectedGraph(),
+				pkg:           pkg,
+			}
+			clusters[pkg] = c
+		}
+		// Nodes are labeled once in main graph, cluster only holds them.
+		c.AddNode(clusterNode{id: tn.ID(), dotID: tn.DOTID()})
+	}
+
+	structure := []dot.Multigraph{}
+	for _, c := range slices.SortedFunc(maps.Values(clusters), func(a, b *packageCluster) int {
+		return strings.Compare(a.path(), b.path())
+	}) {
+		structure = append(structure, c)
+	}
+
+	return structure
+}
+
+// Focus returns graph of types related to t by at most depth relations in
+// any direction, with all relations between them.
+func (g *TypeGraph) Focus(t TypeI, depth int) *TypeGraph {
+	fg := NewTypeGraph()
+
+	start, ok := g.nodes[t]
+	if !ok {
+		return fg
+	}
+
+	seen := map[int64]bool{start.ID(): true}
+	level := []graph.Node{start}
+	for range depth {
+		next := []graph.Node{}
+		for _, n := range level {
+			for _, m := range slices.Concat(graph.NodesOf(g.From(n.ID())), graph.NodesOf(g.To(n.ID()))) {
+				if !seen[m.ID()] {
+					seen[m.ID()] = true
+					next = append(next, m)
+				}
+			}
+		}
+		level = next
+	}
+
+	for _, n := range diagramNodes(g) {
+		if tn, ok := n.(*TypeNode); ok && seen[n.ID()] {
+			fg.AddNode(tn)
+			fg.nodes[tn.Type] = tn
+		}
+	}
+
+	lines := g.Edges()
+	for lines.Next() {
+		e := lines.Edge()
+		if !seen[e.From().ID()] || !seen[e.To().ID()] {
+			continue
+		}
+
+		ls := e.(graph.Lines)
+		for ls.Next() {
+			fg.SetLine(ls.Line())
+		}
+	}
+
+	return fg
+}
+
+// Find returns node of type with full name, or with name when only one
+// type has it.
+func (g *TypeGraph) Find(name string) (*TypeNode, bool) {
+	var found *TypeNode
+	for t, n := range g.nodes {
+		if t.GetFullName() == name {
+			return n, true
+		}
+		if t.GetName() == name {
+			if found != nil {
+				return nil, false
+			}
+			found = n
+		}
+	}
+
+	return found, found != nil
+}
+
+// Attributes labels node with type name, fields and tag summary.
+func (n *TypeNode) Attributes() []encoding.Attribute {
+	var b strings.Builder
+	b.WriteString(`<<TABLE BORDER="0" CELLBORDER="1" CELLSPACING="0" CELLPADDING="4">`)
+
+	title := html.EscapeString(n.Type.GetFullName())
+	if n.Type.GetKind() == KindInterface {
+		title = "&laquo;interface&raquo;<BR/>" + title
+	}
+	fmt.Fprintf(&b, `<TR><TD><B>%s</B></TD></TR>`, title)
+
+	fields := []string{}
+	for f := range n.Type.FieldsSeq() {
+		fields = append(fields, html.EscapeString(f.GetName()+" "+fieldDeclType(f)))
+	}
+	if len(fields) > 0 {
+		fmt.Fprintf(&b, `<TR><TD ALIGN="LEFT" BALIGN="LEFT">%s</TD></TR>`, strings.Join(fields, "<BR/>"))
+	}
+
+	if tags := tagSummary(n.Type.GetTag()); tags != "" {
+		fmt.Fprintf(&b, `<TR><TD ALIGN="LEFT"><I>%s</I></TD></TR>`, html.EscapeString(tags))
+	}
+
+	b.WriteString(`</TABLE>>`)
+
+	return []encoding.Attribute{{Key: "label", Value: b.String()}}
+}
+
+// tagSummary returns tag keys with their scalar values, like
+// "archetype, layer: Game".
+func tagSummary(tag Tag) string {
+	items := []string{}
+	for _, key := range slices.Sorted(maps.Keys(tag.Data)) {
+		switch v := tag.Data[key].(type) {
+		case Tag, TagData, []any:
+			items = append(items, key)
+		default:
+			items = append(items, fmt.Sprintf("%s: %v", key, v))
+		}
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// Attributes styles line by relation kind. Embedded base and extended
+// archetype point to base with hollow arrow, solid and dashed. Subclass is
+// drawn dotted from base. Field is a plain arrow labeled with field name.
+func (l TypeLine) Attributes() []encoding.Attribute {
+	switch l.Kind {
+	case EdgeBase:
+		return []encoding.Attribute{{Key: "arrowhead", Value: "empty"}}
+	case EdgeExtends:
+		return []encoding.Attribute{{Key: "arrowhead", Value: "empty"}, {Key: "style", Value: "dashed"}}
+	case EdgeSubclass:
+		return []encoding.Attribute{{Key: "arrowhead", Value: "none"}, {Key: "arrowtail", Value: "empty"}, {Key: "dir", Value: "back"}, {Key: "style", Value: "dotted"}}
+	}
+
+	if l.Name == "" {
+		return []encoding.Attribute{{Key: "arrowhead", Value: "vee"}}
+	}
+	return []encoding.Attribute{{Key: "arrowhead", Value: "vee"}, {Key: "label", Value: l.Name}}
+}
+
+type attributes []encoding.Attribute
+
+func (a attributes) Attributes() []encoding.Attribute {
+	return a
+}
+
+// clusterNode is a type node declared in a cluster.
+type clusterNode struct {
+	id    int64
+	dotID string
+}
+
+func (n clusterNode) ID() int64 {
+	return n.id
+}
+
+func (n clusterNode) DOTID() string {
+	return n.dotID
+}
+
+// packageCluster is a DOT cluster of types declared in a package.
+type packageCluster struct {
+	*multi.DirectedGraph
+
+	pkg *Package
+}
+
+func (c *packageCluster) path() string {
+	if c.pkg == nil {
+		return ""
+	}
+	if c.pkg.Pkg != nil {
+		return c.pkg.Pkg.PkgPath
+	}
+	return c.pkg.Name
+}
+
+func (c *packageCluster) DOTID() string {
+	return "cluster_" + strings.Map(func(r rune) rune {
+		if r == '/' || r == '.' || r == '-' {
+			return '_'
+		}
+		return r
+	}, c.path())
+}
+
+func (c *packageCluster) DOTAttributers() (graph, node, edge encoding.Attributer) {
+	return attributes{{Key: "label", Value: c.path()}, {Key: "style", Value: "rounded"}},
+		attributes{}, attributes{}
+}
@@ -0,0 +1,223 @@
+package core
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"maps"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ModelVersion is version of PackageDto layout, it changes when exported
+// model changes incompatibly.
+const ModelVersion = 1
+
+// ModelSchema is JSON Schema of PackageDto exported as JSON.
+//
+//go:embed model.schema.json
+var ModelSchema []byte
+
+// PackageDto is exported model of an inspected package. Types refer to
+// other types by full name, so model has no cycles and can be written as
+// YAML or JSON as is.
+type PackageDto struct {
+	Version  int       `yaml:"version" json:"version"`
+	Name     string    `yaml:"name" json:"name"`
+	Path     string    `yaml:"path" json:"path"`
+	BuildTag string    `yaml:"build_tag,omitempty" json:"build_tag,omitempty"`
+	Test     bool      `yaml:"test,omitempty" json:"test,omitempty"`
+	Types    []TypeDto `yaml:"types" json:"types"`
+	Funcs    []FuncDto `yaml:"funcs,omitempty" json:"funcs,omitempty"` // package level functions
+}
+
+type TypeParamDto struct {
+	Name       string `yaml:"name" json:"name"`
+	Constraint string `yaml:"constraint" json:"constraint"`
+}
+
+type TypeDto struct {
+	TokenDto   `yaml:",inline"`
+	Kind       string         `yaml:"kind" json:"kind"`
+	Underlying string         `yaml:"underlying,omitempty" json:"underlying,omitempty"`
+	TypeParams []TypeParamDto `yaml:"type_params,omitempty" json:"type_params,omitempty"`
+	Bases      []FieldDto     `yaml:"bases,omitempty" json:"bases,omitempty"`
+	Extends    []string       `yaml:"extends,omitempty" json:"extends,omitempty"`
+	Subclasses []string       `yaml:"subclasses,omitempty" json:"subclasses,omitempty"`
+	Fields     []FieldDto     `yaml:"fields,omitempty" json:"fields,omitempty"`
+	Funcs      []FuncDto      `yaml:"funcs,omitempty" json:"funcs,omitempty"`
+}
+
+type FieldDto struct {
+	TokenDto `yaml:",inline"`
+	Type     string   `yaml:"type,omitempty" json:"type,omitempty"` // full name of resolved type, empty if not resolved
+	Decl     string   `yaml:"decl" json:"decl"`                     // type as declared
+	Array    bool     `yaml:"array,omitempty" json:"array,omitempty"`
+	Inline   *TypeDto `yaml:"inline,omitempty" json:"inline,omitempty"` // anonymous struct type
+}
+
+type ParameterDto struct {
+	Name     string `yaml:"name,omitempty" json:"name,omitempty"`
+	Type     string `yaml:"type" json:"type"`
+	Variadic bool   `yaml:"variadic,omitempty" json:"variadic,omitempty"`
+}
+
+type FuncDto struct {
+	TokenDto  `yaml:",inline"`
+	Receiver  string         `yaml:"receiver,omitempty" json:"receiver,omitempty"` // receiver declaration of method, like (p *Pool[T])
+	Arguments []ParameterDto `yaml:"arguments,omitempty" json:"arguments,omitempty"`
+	Results   []ParameterDto `yaml:"results,omitempty" json:"results,omitempty"`
+}
+
+func MakeTypeDto(t TypeI) TypeDto {
+	dto := TypeDto{
+		TokenDto: MakeTokenDto(t),
+		Kind:     t.GetKind().String(),
+	}
+
+	for _, p := range t.GetTypeParams() {
+		dto.TypeParams = append(dto.TypeParams, TypeParamDto{Name: p.Name, Constraint: p.Constraint})
+	}
+	for f := range t.BasesSeq() {
+		dto.Bases = append(dto.Bases, MakeFieldDto(f))
+	}
+	for f := range t.FieldsSeq() {
+		dto.Fields = append(dto.Fields, MakeFieldDto(f))
+	}
+
+	funcs := slices.SortedFunc(t.FuncsSeq(), func(a, b FuncI) int {
+		return cmp.Compare(a.GetName(), b.GetName())
+	})
+	for _, f := range funcs {
+		dto.Funcs = append(dto.Funcs, MakeFuncDto(f))
+	}
+
+	if et, ok := t.(*Type); ok {
+		dto.Underlying = et.Underlying
+		dto.Extends = typeNames(et.Extends)
+		dto.Subclasses = typeNames(et.Subclasses)
+		if len(et.Funcs) == 0 {
+			// Interface methods are attached to Funcs by Prepare only.
+			for _, f := range et.Methods {
+				dto.Funcs = append(dto.Funcs, MakeFuncDto(f))
+			}
+		}
+	}
+
+	return dto
+}
+
+func MakeFieldDto(f FieldI) FieldDto {
+	dto := FieldDto{
+		TokenDto: MakeTokenDto(f),
+		Decl:     f.DeclType(),
+		Array:    f.IsArray(),
+	}
+	if dto.Decl == "" {
+		dto.Decl = f.GetTypeName()
+	}
+
+	if t := f.GetType(); t != nil {
+		if et, ok := t.(*Type); ok && et.Inline {
+			inline := MakeTypeDto(t)
+			dto.Inline = &inline
+		} else {
+			dto.Type = t.GetFullName()
+		}
+	}
+
+	return dto
+}
+
+func MakeFuncDto(f FuncI) FuncDto {
+	dto := FuncDto{
+		TokenDto: MakeTokenDto(f),
+	}
+
+	if ef, ok := CastFunc(f); ok {
+		if ef.Receiver != nil {
+			dto.Receiver = ef.Receiver.Decl()
+		}
+		dto.Arguments = parameterDtos(ef.Arguments)
+		dto.Results = parameterDtos(ef.Results)
+	}
+
+	return dto
+}
+
+func parameterDtos(params []Parameter) []ParameterDto {
+	dtos := make([]ParameterDto, 0, len(params))
+	for _, p := range params {
+		dtos = append(dtos, ParameterDto{
+			Name:     p.Name,
+			Type:     p.Type,
+			Variadic: p.Variadic,
+		})
+	}
+	return dtos
+}
+
+func typeNames(ts []TypeI) []string {
+	names := make([]string, 0, len(ts))
+	for _, t := range ts {
+		names = append(names, t.GetFullName())
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (t Type) MarshalYAML() (interface{}, error) {
+	return MakeTypeDto(&t), nil
+}
+
+func (f Field) MarshalYAML() (interface{}, error) {
+	return MakeFieldDto(&f), nil
+}
+
+func (f Func) MarshalYAML() (interface{}, error) {
+	return MakeFuncDto(&f), nil
+}
+
+// Model returns exported model of types and functions inspected from pkg.
+func (g *GeneratorBaseT) Model(pkg *Package) PackageDto {
+	dto := PackageDto{
+		Version:  ModelVersion,
+		Name:     pkg.Name,
+		BuildTag: pkg.BuildTag,
+		Test:     pkg.Test,
+		Types:    []TypeDto{},
+	}
+	if pkg.Pkg != nil {
+		dto.Path = pkg.Pkg.PkgPath
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(g.Types)) {
+		if t := g.Types[name]; t.GetPackage() == pkg {
+			dto.Types = append(dto.Types, MakeTypeDto(t))
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(g.Funcs)) {
+		for _, f := range g.Funcs[name] {
+			if ef, ok := CastFunc(f); ok && ef.IsMethod() {
+				continue
+			}
+			if f.GetPackage() == pkg {
+				dto.Funcs = append(dto.Funcs, MakeFuncDto(f))
+			}
+		}
+	}
+
+	return dto
+}
+
+// Yaml returns Model of pkg as YAML.
+func (g *GeneratorBaseT) Yaml(pkg *Package) ([]byte, error) {
+	return yaml.Marshal(g.Model(pkg))
+}
+
+// Json returns Model of pkg as JSON described by ModelSchema.
+func (g *GeneratorBaseT) Json(pkg *Package) ([]byte, error) {
+	return json.MarshalIndent(g.Model(pkg), "", "  ")
+}
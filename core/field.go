@@ -1,6 +1,10 @@
 package core
 
-import "fmt"
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+)
 
 type FieldI interface {
 	TokenI
@@ -8,6 +12,8 @@ type FieldI interface {
 	IsMeta() bool
 	IsArray() bool
 	GetType() TypeI
+	GetTypeArgs() []types.Type
+	GetGoType() types.Type
 	GetTypeName() string
 	DeclType() string
 }
@@ -19,6 +25,13 @@ type FieldBuilder interface {
 	Prepare(tf TypeFactory) error
 }
 
+// InlineFieldBuilder is implemented by fields which type can be declared
+// inline, like anonymous struct.
+type InlineFieldBuilder interface {
+	InlineStruct() *ast.StructType
+	SetInlineType(t TypeI)
+}
+
 type Field struct {
 	Token
 	OwnerType        TypeI
@@ -26,7 +39,11 @@ type Field struct {
 	TypeName         string
 	PackagedTypeName string
 	CallTypeName     string
+	GoType           types.Type   // Resolved type of field, nil if type info is not available.
+	TypeArgs         []types.Type // Type arguments of generic field type.
 	decltype         string
+	expr             ast.Expr
+	inline           *ast.StructType
 	IsArray_         bool
 }
 
@@ -47,6 +64,14 @@ func (f Field) GetType() TypeI {
 	return f.Type
 }
 
+func (f Field) GetTypeArgs() []types.Type {
+	return f.TypeArgs
+}
+
+func (f Field) GetGoType() types.Type {
+	return f.GoType
+}
+
 func (f Field) GetTypeName() string {
 	return f.TypeName
 }
@@ -64,7 +89,33 @@ func (f *Field) SetPackagedTypeName(name string) {
 	//f.CallTypeName = name
 }
 
+func (f *Field) InlineStruct() *ast.StructType {
+	return f.inline
+}
+
+func (f *Field) SetInlineType(t TypeI) {
+	f.Type = t
+}
+
 func (f *Field) Prepare(tf TypeFactory) error {
+	if f.inline != nil {
+		return nil
+	}
+
+	if f.Package != nil && f.Package.Pkg != nil && f.Package.Pkg.TypesInfo != nil && f.expr != nil {
+		f.GoType = f.Package.Pkg.TypesInfo.TypeOf(f.expr)
+		f.TypeArgs = TypeArgs(f.GoType)
+		if _, ok := types.Unalias(ElemType(f.GoType)).(*types.TypeParam); ok {
+			return nil // type parameter has no Type, see FieldTypeName
+		}
+		if obj := NamedObject(f.GoType); obj != nil {
+			if t, ok := tf.GetTypeByObject(obj); ok {
+				f.Type = t
+				return nil
+			}
+		}
+	}
+
 	var ok bool
 	f.Type, ok = tf.GetType(f.PackagedTypeName)
 	if !ok {
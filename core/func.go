@@ -3,18 +3,20 @@ package core
 import (
 	"fmt"
 	"go/ast"
+	"go/types"
 	"iter"
 	"slices"
 	"strings"
 
 	"deedles.dev/xiter"
-	"github.com/igadmg/goex/astex"
 )
 
 type Parameter struct {
 	Name     string
-	Type     string
+	Type     string // type as declared, ...T for variadic parameter
 	DeclType string
+	IsError  bool // parameter is of built in error type
+	Variadic bool
 }
 
 func AstFieldName(decl *ast.Field) (string, error) {
@@ -25,33 +27,169 @@ func AstFieldName(decl *ast.Field) (string, error) {
 	return "", fmt.Errorf("'Names' not found")
 }
 
+// MakeParameter returns parameter for first name of decl. Unnamed parameter
+// is returned with empty Name.
 func MakeParameter(decl *ast.Field) (Parameter, error) {
-	name, err := AstFieldName(decl)
-	if err != nil {
-		return Parameter{}, err
-	}
-
-	ptype, _ := astex.GetFieldDeclTypeName(decl.Type)
+	name, _ := AstFieldName(decl)
 
 	p := Parameter{
 		Name: name,
-		Type: ptype,
 	}
+
+	ptype := decl.Type
+	if e, ok := ptype.(*ast.Ellipsis); ok {
+		p.Variadic = true
+		ptype = e.Elt
+	}
+
+	p.Type = types.ExprString(ptype)
+	if p.Variadic {
+		p.Type = "..." + p.Type
+	}
+
+	ident, ok := ptype.(*ast.Ident)
+	p.IsError = ok && ident.Name == "error"
+
 	return p, nil
 }
 
+// MakeParameters returns function parameters. Every name of grouped
+// parameters gets its own Parameter, unnamed and blank parameters get
+// placeholder names p<index>, so they can be both declared and passed on.
+// Placeholder taken by declared parameter gets underscores appended.
+func MakeParameters(list *ast.FieldList) ([]Parameter, error) {
+	params, err := makeParameters(list)
+	if err != nil {
+		return nil, err
+	}
+
+	taken := map[string]bool{}
+	for _, p := range params {
+		taken[p.Name] = true
+	}
+	placeholders(params, taken)
+
+	return params, nil
+}
+
+// placeholders names unnamed and blank params p<index>, avoiding and
+// marking taken names.
+func placeholders(params []Parameter, taken map[string]bool) {
+	for i := range params {
+		if params[i].Name == "" || params[i].Name == "_" {
+			params[i].Name = uniqueName(fmt.Sprintf("p%d", i), taken)
+		}
+	}
+}
+
+// MakeResults returns function results. Every name of grouped results gets
+// its own Parameter, unnamed results have empty Name.
+func MakeResults(list *ast.FieldList) ([]Parameter, error) {
+	return makeParameters(list)
+}
+
+func makeParameters(list *ast.FieldList) ([]Parameter, error) {
+	if list == nil {
+		return nil, nil
+	}
+
+	params := []Parameter{}
+	for _, decl := range list.List {
+		p, err := MakeParameter(decl)
+		if err != nil {
+			return nil, err
+		}
+		if len(decl.Names) == 0 {
+			params = append(params, p)
+			continue
+		}
+
+		for _, name := range decl.Names {
+			p.Name = name.Name
+			params = append(params, p)
+		}
+	}
+
+	return params, nil
+}
+
+// Receiver is a method receiver, like (p *Pool[T]).
+type Receiver struct {
+	Name       string   // receiver variable name, may be empty
+	TypeName   string   // receiver type name without pointer and type parameters
+	Type       TypeI    // receiver type, set when method is attached to it
+	Pointer    bool     // receiver is a pointer
+	TypeParams []string // receiver type parameters of generic type
+}
+
+// MakeReceiver returns receiver from method receiver declaration.
+func MakeReceiver(decl *ast.Field) (Receiver, error) {
+	r := Receiver{}
+	if len(decl.Names) > 0 {
+		r.Name = decl.Names[0].Name
+	}
+
+	expr := ast.Unparen(decl.Type)
+	if se, ok := expr.(*ast.StarExpr); ok {
+		r.Pointer = true
+		expr = ast.Unparen(se.X)
+	}
+
+	switch e := expr.(type) {
+	case *ast.IndexExpr:
+		r.TypeParams = []string{types.ExprString(e.Index)}
+		expr = e.X
+	case *ast.IndexListExpr:
+		for _, i := range e.Indices {
+			r.TypeParams = append(r.TypeParams, types.ExprString(i))
+		}
+		expr = e.X
+	}
+
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return r, fmt.Errorf("unsupported receiver type %s", types.ExprString(decl.Type))
+	}
+	r.TypeName = ident.Name
+
+	return r, nil
+}
+
+// DeclType returns receiver type as written in declaration, like *Pool[T].
+func (r Receiver) DeclType() string {
+	name := r.TypeName
+	if len(r.TypeParams) > 0 {
+		name += "[" + strings.Join(r.TypeParams, ", ") + "]"
+	}
+	if r.Pointer {
+		name = "*" + name
+	}
+	return name
+}
+
+// Decl returns receiver declaration, like (p *Pool[T]).
+func (r Receiver) Decl() string {
+	if r.Name == "" {
+		return "(" + r.DeclType() + ")"
+	}
+	return "(" + r.Name + " " + r.DeclType() + ")"
+}
+
 type FuncI interface {
 	TokenI
 
 	GetFullTypeName() string
+	// GetTypeKey returns TypeKey of receiver type, empty for functions.
+	GetTypeKey() string
 }
 
 type Func struct {
 	Token
 	FType     string
 	DeclType  string
+	Receiver  *Receiver // nil for functions
 	Arguments []Parameter
-	Return    Type
+	Results   []Parameter
 }
 
 func MakeFunc(pkg *Package) Func {
@@ -71,8 +209,57 @@ func (f *Func) New() *Func {
 	return f
 }
 
+// SetFuncType fills arguments and results from function type. Receiver
+// must be set before, argument placeholders avoid names of receiver,
+// arguments and results.
+func (f *Func) SetFuncType(ft *ast.FuncType) (err error) {
+	if f.Arguments, err = makeParameters(ft.Params); err != nil {
+		return err
+	}
+	if f.Results, err = MakeResults(ft.Results); err != nil {
+		return err
+	}
+
+	placeholders(f.Arguments, f.declaredNames())
+	return nil
+}
+
+// declaredNames returns names of receiver, arguments and results.
+func (f *Func) declaredNames() map[string]bool {
+	taken := map[string]bool{}
+	if f.Receiver != nil && f.Receiver.Name != "" {
+		taken[f.Receiver.Name] = true
+	}
+	for _, p := range slices.Concat(f.Arguments, f.Results) {
+		if p.Name != "" {
+			taken[p.Name] = true
+		}
+	}
+
+	return taken
+}
+
+// GetFullTypeName returns package qualified name of receiver type, same as
+// TypeI.GetFullName of the type method belongs to. Empty for functions.
 func (f Func) GetFullTypeName() string {
-	return f.FType
+	if f.FType == "" || f.Package == nil {
+		return f.FType
+	}
+
+	return f.Package.Name + "." + f.FType
+}
+
+func (f Func) GetTypeKey() string {
+	if f.FType == "" {
+		return ""
+	}
+
+	return TypeKey(f.Package, f.FType)
+}
+
+// IsMethod reports whether f has a receiver.
+func (f Func) IsMethod() bool {
+	return f.Receiver != nil
 }
 
 func CastFunc(f FuncI) (t *Func, ok bool) {
@@ -121,7 +308,70 @@ func (f *Func) DeclArguments() string {
 func (f *Func) CallArguments() string {
 	return strings.Join(slices.Collect(
 		xiter.Map(slices.Values(f.Arguments), func(arg Parameter) string {
+			if arg.Variadic {
+				return arg.Name + "..."
+			}
 			return arg.Name
 		})),
 		", ")
 }
+
+// ReturnsError reports whether last result of function is an error.
+func (f *Func) ReturnsError() bool {
+	return len(f.Results) > 0 && f.Results[len(f.Results)-1].IsError
+}
+
+// DeclResults returns results as written in function declaration, like
+// "int", "(int, error)" or "(n int, err error)".
+func (f *Func) DeclResults() string {
+	if len(f.Results) == 0 {
+		return ""
+	}
+
+	if len(f.Results) == 1 && f.Results[0].Name == "" {
+		return f.Results[0].Type
+	}
+
+	return "(" + strings.Join(slices.Collect(
+		xiter.Map(slices.Values(f.Results), func(r Parameter) string {
+			if r.Name == "" {
+				return r.Type
+			}
+			return fmt.Sprintf("%s %s", r.Name, r.Type)
+		})),
+		", ") + ")"
+}
+
+// CallResults returns names to assign function results to, like "n, err".
+// Unnamed results get err for last error result and r<index> for others,
+// not colliding with receiver, argument and result names.
+func (f *Func) CallResults() string {
+	return strings.Join(f.resultNames(), ", ")
+}
+
+func (f *Func) resultNames() []string {
+	taken := f.declaredNames()
+	names := make([]string, 0, len(f.Results))
+	for i, r := range f.Results {
+		switch {
+		case r.Name != "" && r.Name != "_":
+			names = append(names, r.Name)
+		case r.IsError && i == len(f.Results)-1:
+			names = append(names, uniqueName("err", taken))
+		default:
+			names = append(names, uniqueName(fmt.Sprintf("r%d", i), taken))
+		}
+	}
+	return names
+}
+
+// uniqueName returns name, with underscores appended if it is taken, and
+// marks it taken.
+func uniqueName(name string, taken map[string]bool) string {
+	for taken[name] {
+		name += "_"
+	}
+	taken[name] = true
+
+	return name
+}
@@ -2,12 +2,14 @@ package core
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"go/ast"
+	"go/types"
+	"maps"
 	"slices"
 	"strings"
 
-	"deedles.dev/xiter"
 	"github.com/igadmg/goex/astex"
 	"github.com/igadmg/goex/gx"
 	"golang.org/x/tools/go/packages"
@@ -18,9 +20,10 @@ type TypeFactory interface {
 	GetPackage() *Package
 	NewType(pkg *Package, t TypeI, spec *ast.TypeSpec) (TypeI, error)
 	NewField(f FieldI, spec *ast.Field) (FieldI, error)
-	NewFunc(f FuncI, spec *ast.FuncDecl) (FuncI, error)
+	NewFunc(pkg *Package, f FuncI, spec *ast.FuncDecl) (FuncI, error)
 
 	GetType(name string) (t TypeI, ok bool)
+	GetTypeByObject(obj types.Object) (t TypeI, ok bool)
 	GetFuncs(t TypeI) []FuncI
 }
 
@@ -31,12 +34,41 @@ type Generator interface {
 	Tags() []string
 
 	Prepare()
-	Generate(pkg *Package) bytes.Buffer
-
-	Yaml(fileName string)
+	// Generate returns generated code for pkg and diagnostics about its source.
+	// Diagnostics with error severity fail generation same as returned error.
+	Generate(pkg *Package) (bytes.Buffer, Diagnostics, error)
+
+	// Yaml returns model of pkg written to 0.gen_<flag>.yaml.
+	Yaml(pkg *Package) ([]byte, error)
+	// Json returns model of pkg written to 0.gen_<flag>.json, see ModelSchema.
+	Json(pkg *Package) ([]byte, error)
 	Graph() graph.Graph
 }
 
+// FlagsRegistrar is implemented by generators which take own command line
+// options.
+type FlagsRegistrar interface {
+	// RegisterFlags registers generator flags on fs. Every flag name is
+	// prefixed with prefix, which is generator Flag() followed by a dot,
+	// like -ecs.layer.
+	RegisterFlags(fs *flag.FlagSet, prefix string)
+	// FlagsParsed is called once flags are parsed, before Prepare.
+	FlagsParsed() error
+}
+
+// Configurable is implemented by generators which accept settings from
+// project configuration file. Configure is called before Generate with
+// settings effective for the package being generated.
+type Configurable interface {
+	Configure(settings map[string]any) error
+}
+
+// Invalidator is implemented by generators which can forget everything
+// inspected from a package, so the package can be inspected again.
+type Invalidator interface {
+	Invalidate(pkg *Package)
+}
+
 type GeneratorBase struct {
 	cfg *packages.Config
 	Pkg *Package
@@ -44,6 +76,8 @@ type GeneratorBase struct {
 	flag string   // cmd line flags
 	tags []string // tag names
 
+	diagnostics Diagnostics
+
 	//logf func(format string, args ...any) // test logging hook; nil when not testing
 }
 
@@ -59,28 +93,123 @@ func (g *GeneratorBase) GetPackage() *Package {
 	return g.Pkg
 }
 
+func (g *GeneratorBase) Errorf(t TokenI, format string, args ...any) {
+	g.diagnostics.Errorf(t, format, args...)
+}
+
+func (g *GeneratorBase) Warnf(t TokenI, format string, args ...any) {
+	g.diagnostics.Warnf(t, format, args...)
+}
+
+// TakeDiagnostics returns diagnostics reported so far and clears them, so
+// each one is returned from Generate only once.
+func (g *GeneratorBase) TakeDiagnostics() Diagnostics {
+	d := g.diagnostics
+	g.diagnostics = nil
+	return d
+}
+
+// TypeImportName returns package qualifier of t followed by dot, the one
+// LocalTypeName uses. Empty for types of generated package, inline types
+// and type parameters, which have no TypeI.
 func (g *GeneratorBase) TypeImportName(t TypeI) string {
 	if t == nil {
-		return "<unknown type>"
+		return ""
+	}
+
+	if it, ok := t.(*Type); ok && it.Inline {
+		return ""
 	}
 
-	if t.GetPackage() == g.Pkg {
+	if t.GetPackage().Same(g.Pkg) {
 		return ""
 	}
 
 	return t.GetPackage().Name + "."
 }
 
-func (g *GeneratorBase) LocalTypeName(t TypeI) string {
+// LocalTypeName returns name of t as used in generated package. Generic
+// type is instantiated with targs or with its own type parameters if no
+// targs given.
+func (g *GeneratorBase) LocalTypeName(t TypeI, targs ...types.Type) string {
 	if t == nil {
 		return "<unknown type>"
 	}
 
-	if t.GetPackage() == g.Pkg {
-		return t.GetName()
+	if it, ok := t.(*Type); ok && it.Inline {
+		return it.Underlying
 	}
 
-	return t.GetFullName()
+	name := t.GetFullName()
+	if t.GetPackage().Same(g.Pkg) {
+		name = t.GetName()
+	}
+
+	if len(targs) > 0 {
+		args := make([]string, 0, len(targs))
+		for _, a := range targs {
+			args = append(args, types.TypeString(a, g.Qualifier))
+		}
+		return name + "[" + strings.Join(args, ", ") + "]"
+	}
+
+	if params := t.GetTypeParams(); len(params) > 0 {
+		args := make([]string, 0, len(params))
+		for _, p := range params {
+			args = append(args, p.Name)
+		}
+		return name + "[" + strings.Join(args, ", ") + "]"
+	}
+
+	return name
+}
+
+// FieldTypeName returns field type as used in generated package, with
+// pointers, slices and type arguments, like *Vec[T]. Type parameter is
+// returned by its name.
+func (g *GeneratorBase) FieldTypeName(f FieldI) string {
+	if it, ok := f.GetType().(*Type); ok && it.Inline {
+		return f.DeclType()
+	}
+
+	if gt := f.GetGoType(); gt != nil {
+		return types.TypeString(gt, g.Qualifier)
+	}
+
+	// Without type info wrappers are taken from declaration.
+	return typeWrappers(f.DeclType()) + g.LocalTypeName(f.GetType(), f.GetTypeArgs()...)
+}
+
+// typeWrappers returns pointers, slices and arrays decl starts with, like
+// "[]*" of "[]*Vec".
+func typeWrappers(decl string) string {
+	i := 0
+	for i < len(decl) {
+		switch decl[i] {
+		case '*':
+			i++
+		case '[':
+			n := strings.IndexByte(decl[i:], ']')
+			if n < 0 {
+				return decl[:i]
+			}
+			i += n + 1
+		default:
+			return decl[:i]
+		}
+	}
+
+	return decl[:i]
+}
+
+// Qualifier is a types.Qualifier which names packages relative to generated
+// package.
+func (g *GeneratorBase) Qualifier(p *types.Package) string {
+	if g.Pkg != nil && g.Pkg.Pkg != nil && p.Path() == g.Pkg.Pkg.PkgPath {
+		return ""
+	}
+
+	return p.Name()
 }
 
 type GeneratorBaseT struct {
@@ -88,9 +217,10 @@ type GeneratorBaseT struct {
 
 	G Generator
 
-	Types  map[string]TypeI
-	Fields []FieldI
-	Funcs  map[string][]FuncI
+	Types         map[string]TypeI // Types by TypeKey.
+	TypesByObject map[string]TypeI // Types by ObjectKey of their declaration.
+	Fields        []FieldI
+	Funcs         map[string][]FuncI // Methods by TypeKey of receiver type, functions under empty key.
 }
 
 func MakeGeneratorB(flag string, tags ...string) GeneratorBaseT {
@@ -99,44 +229,122 @@ func MakeGeneratorB(flag string, tags ...string) GeneratorBaseT {
 			flag: flag,
 			tags: tags,
 		},
-		Types:  map[string]TypeI{},
-		Fields: []FieldI{},
-		Funcs:  map[string][]FuncI{},
+		Types:         map[string]TypeI{},
+		TypesByObject: map[string]TypeI{},
+		Fields:        []FieldI{},
+		Funcs:         map[string][]FuncI{},
 	}
 }
 
-func (g *GeneratorBaseT) NewType(pkg *Package, t TypeI, spec *ast.TypeSpec) (TypeI, error) {
+func (g *GeneratorBaseT) NewType(pkg *Package, t TypeI, spec *ast.TypeSpec) (_ TypeI, err error) {
 	if t == nil {
 		t = NewType(pkg)
 		defer func() {
-			g.Types[t.GetFullName()] = t
+			if err != nil {
+				return
+			}
+
+			g.Types[TypeKey(pkg, t.GetName())] = t
+			if obj, ok := pkg.Defs[spec.Name]; ok && obj != nil {
+				g.TypesByObject[ObjectKey(obj)] = t
+			}
 		}()
 	}
 
 	switch et := t.(type) {
 	case *Type:
-		ttype, ok := spec.Type.(*ast.StructType)
-		if !ok {
-			return nil, fmt.Errorf("can parse only *ast.StructType")
+		et.Name = spec.Name.Name
+		et.Pos = spec.Name.Pos()
+
+		if spec.TypeParams != nil {
+			for _, field := range spec.TypeParams.List {
+				constraint := types.ExprString(field.Type)
+				for _, name := range field.Names {
+					et.TypeParams = append(et.TypeParams, TypeParam{
+						Name:       name.Name,
+						Constraint: constraint,
+					})
+				}
+			}
 		}
 
-		et.Name = spec.Name.Name
+		if spec.Assign.IsValid() {
+			et.Kind = KindAlias
+			et.Underlying = types.ExprString(spec.Type)
+			break
+		}
+
+		switch ttype := spec.Type.(type) {
+		case *ast.StructType:
+			et.Kind = KindStruct
+			g.newStructFields(et, ttype)
+		case *ast.InterfaceType:
+			et.Kind = KindInterface
+			if err := g.newInterfaceMethods(et, ttype); err != nil {
+				return nil, err
+			}
+		case *ast.FuncType:
+			et.Kind = KindFunc
+			et.Underlying = types.ExprString(spec.Type)
+			et.Signature = NewFunc(et.Package)
+			et.Signature.Pos = ttype.Pos()
+			if err := et.Signature.SetFuncType(ttype); err != nil {
+				return nil, err
+			}
+		default:
+			et.Kind = KindNamed
+			et.Underlying = types.ExprString(spec.Type)
+		}
+	}
+
+	return t, nil
+}
+
+func setFieldOwner(et *Type, f FieldI) {
+	f.SetPackage(et.Package)
+	if fb, ok := f.(FieldBuilder); ok {
+		fb.SetOwnerType(et)
+		tp := strings.Split(f.GetTypeName(), ".")
+		if len(tp) == 1 {
+			fb.SetPackagedTypeName(et.Package.Name + "." + f.GetTypeName())
+		} else {
+			fb.SetPackagedTypeName(f.GetTypeName())
+		}
+	}
+}
+
+func (g *GeneratorBaseT) newStructFields(et *Type, ttype *ast.StructType) {
+	fieldCount := 0
+	for _, field := range ttype.Fields.List {
+		// One field per declared name, X, Y float32 gives two fields.
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{nil}
+		}
 
-		fieldCount := 0
-		for _, field := range ttype.Fields.List {
+		for _, name := range names {
 			f, err := g.G.NewField(nil, field)
 			if err != nil {
 				fieldCount++
 				continue
 			}
 
-			if fb, ok := f.(FieldBuilder); ok {
-				fb.SetOwnerType(t)
-				tp := strings.Split(f.GetTypeName(), ".")
-				if len(tp) == 1 {
-					fb.SetPackagedTypeName(et.Package.Name + "." + f.GetTypeName())
-				} else {
-					fb.SetPackagedTypeName(f.GetTypeName())
+			if fm, ok := f.(TokenM); ok && name != nil {
+				fm.SetName(name.Name)
+				fm.SetPos(name.Pos())
+			}
+
+			setFieldOwner(et, f)
+
+			if fb, ok := f.(InlineFieldBuilder); ok {
+				if st := fb.InlineStruct(); st != nil {
+					it := NewType(et.Package)
+					it.Kind = KindStruct
+					it.Inline = true
+					it.Pos = st.Pos()
+					it.Underlying = types.ExprString(st)
+					g.newStructFields(it, st)
+					fb.SetInlineType(it)
 				}
 			}
 
@@ -154,10 +362,57 @@ func (g *GeneratorBaseT) NewType(pkg *Package, t TypeI, spec *ast.TypeSpec) (Typ
 			fieldCount++
 			et.Fields = append(et.Fields, f)
 		}
-		et.isZero = fieldCount == 0
 	}
+	et.isZero = fieldCount == 0
+}
 
-	return t, nil
+// newInterfaceMethods fills interface methods. Embedded interfaces are stored
+// as base fields, same as embedded structs.
+func (g *GeneratorBaseT) newInterfaceMethods(et *Type, ttype *ast.InterfaceType) error {
+	for _, field := range ttype.Methods.List {
+		ftype, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			f, err := g.G.NewField(nil, field)
+			if err != nil {
+				continue
+			}
+
+			setFieldOwner(et, f)
+			et.BaseFields = append(et.BaseFields, f)
+			continue
+		}
+
+		for _, name := range field.Names {
+			m := NewFunc(et.Package)
+			m.Name = name.Name
+			m.Pos = name.Pos()
+			m.FType = et.Name
+			m.DeclType = et.Name
+			if err := m.SetFuncType(ftype); err != nil {
+				return fmt.Errorf("method %s: %w", m.Name, err)
+			}
+			et.Methods = append(et.Methods, m)
+		}
+	}
+
+	return nil
+}
+
+// inlineStruct returns anonymous struct declared as field type, possibly
+// behind pointer or slice.
+func inlineStruct(expr ast.Expr) *ast.StructType {
+	for {
+		switch e := expr.(type) {
+		case *ast.StructType:
+			return e
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ArrayType:
+			expr = e.Elt
+		default:
+			return nil
+		}
+	}
 }
 
 func (g *GeneratorBaseT) NewField(f FieldI, spec *ast.Field) (FieldI, error) {
@@ -172,10 +427,23 @@ func (g *GeneratorBaseT) NewField(f FieldI, spec *ast.Field) (FieldI, error) {
 	case *Field:
 		var ok bool
 
+		ef.Pos = spec.Pos()
+		ef.expr = spec.Type
 		if len(spec.Names) > 0 {
 			ef.Name = spec.Names[0].Name
 		}
 
+		_, ef.IsArray_ = spec.Type.(*ast.ArrayType)
+		ef.Tag, _ = ParseTag(spec.Tag)
+
+		if st := inlineStruct(spec.Type); st != nil {
+			ef.inline = st
+			ef.decltype = types.ExprString(spec.Type)
+			ef.TypeName = types.ExprString(st)
+			ef.CallTypeName = ef.TypeName
+			break
+		}
+
 		ef.decltype, ok = astex.GetFieldDeclTypeName(spec.Type)
 		if !ok {
 			return nil, fmt.Errorf("failed to get field decl type name")
@@ -191,28 +459,30 @@ func (g *GeneratorBaseT) NewField(f FieldI, spec *ast.Field) (FieldI, error) {
 			return nil, fmt.Errorf("failed to get call type name")
 		}
 
-		_, ef.IsArray_ = spec.Type.(*ast.ArrayType)
-
-		ef.Tag, _ = ParseTag(spec.Tag)
 		//ef.isComponent = err == nil
 	}
 
 	return f, nil
 }
 
-func (g *GeneratorBaseT) NewFunc(f FuncI, decl *ast.FuncDecl) (FuncI, error) {
+func (g *GeneratorBaseT) NewFunc(pkg *Package, f FuncI, decl *ast.FuncDecl) (_ FuncI, err error) {
 	if f == nil {
-		f = NewFunc(g.Pkg)
+		f = NewFunc(pkg)
 		defer func() {
-			if id := f.GetFullTypeName(); id != "" {
-				g.Funcs[id] = append(g.Funcs[id], f)
+			if err != nil {
+				return
 			}
+
+			// Package level functions are kept under empty type key.
+			id := f.GetTypeKey()
+			g.Funcs[id] = append(g.Funcs[id], f)
 		}()
 	}
 
 	switch ef := f.(type) {
 	case *Func:
 		ef.Name = decl.Name.Name
+		ef.Pos = decl.Name.Pos()
 
 		if decl.Doc != nil {
 			doc := decl.Doc.Text()
@@ -227,45 +497,117 @@ func (g *GeneratorBaseT) NewFunc(f FuncI, decl *ast.FuncDecl) (FuncI, error) {
 			}
 		}
 
-		rtype, ok := astex.FuncDeclRecvType(decl)
-		if ok {
-			ef.FType, ok = astex.ExprGetFullTypeName(rtype)
-			if !ok {
-				return nil, fmt.Errorf("failed to get full type name")
-			}
-
-			ef.DeclType, ok = astex.GetFieldDeclTypeName(rtype)
-			if !ok {
-				return nil, fmt.Errorf("failed to get field type name")
+		if decl.Recv != nil && len(decl.Recv.List) > 0 {
+			recv, err := MakeReceiver(decl.Recv.List[0])
+			if err != nil {
+				return nil, err
 			}
+			ef.Receiver = &recv
+			ef.FType = recv.TypeName
+			ef.DeclType = types.ExprString(decl.Recv.List[0].Type)
 		}
 
-		params, ok := astex.FuncDeclParams(decl)
-		if ok {
-			ef.Arguments = slices.Collect(
-				xiter.Map(slices.Values(params), func(f *ast.Field) Parameter {
-					p, _ := MakeParameter(f)
-					return p
-				}))
+		if err := ef.SetFuncType(decl.Type); err != nil {
+			return nil, err
 		}
 	}
 
 	return f, nil
 }
 
+// GetType returns type by TypeKey or by name as used in sources: Name for
+// types of generated package, pkg.Name for others, where pkg is import name
+// in generated package or package name. Package name must be unique among
+// inspected packages.
 func (g *GeneratorBaseT) GetType(name string) (t TypeI, ok bool) {
 	name = strings.TrimLeft(name, " *")
-	names := strings.Split(name, ".")
-	if len(names) > 1 {
-		t, ok = g.Types[name]
-	} else {
-		t, ok = g.Types[g.Pkg.Name+"."+name]
+	if t, ok = g.Types[name]; ok {
+		return
 	}
+
+	i := strings.LastIndexByte(name, '.')
+	if i < 0 {
+		if g.Pkg == nil {
+			return nil, false
+		}
+		t, ok = g.Types[TypeKey(g.Pkg, name)]
+		return
+	}
+	qual, tname := name[:i], name[i+1:]
+
+	if g.Pkg != nil {
+		if qual == g.Pkg.Name {
+			if t, ok = g.Types[TypeKey(g.Pkg, tname)]; ok {
+				return
+			}
+		}
+		if n, imported := g.Pkg.ImportsByName[qual]; imported {
+			if t, ok = g.Types[g.Pkg.Imports[n-1].Path+"."+tname]; ok {
+				return
+			}
+		}
+	}
+
+	t, ok = nil, false
+	for _, tt := range g.Types {
+		if tt.GetFullName() != name {
+			continue
+		}
+		if ok {
+			return nil, false // ambiguous package name
+		}
+		t, ok = tt, true
+	}
+	return
+}
+
+func (g *GeneratorBaseT) GetTypeByObject(obj types.Object) (t TypeI, ok bool) {
+	t, ok = g.TypesByObject[ObjectKey(obj)]
 	return
 }
 
+// GetFuncs returns methods of t declared in the same Package as t. Methods
+// declared in test files of the package are not returned for non-test type.
 func (g *GeneratorBaseT) GetFuncs(t TypeI) []FuncI {
-	return g.Funcs[t.GetName()]
+	return slices.DeleteFunc(slices.Clone(g.Funcs[TypeKey(t.GetPackage(), t.GetName())]), func(f FuncI) bool {
+		return f.GetPackage() != t.GetPackage()
+	})
+}
+
+var _ Invalidator = (*GeneratorBaseT)(nil)
+
+func (g *GeneratorBaseT) Invalidate(pkg *Package) {
+	fromPkg := func(t TokenI) bool {
+		return t.GetPackage() == pkg
+	}
+
+	maps.DeleteFunc(g.Types, func(_ string, t TypeI) bool {
+		return fromPkg(t)
+	})
+	maps.DeleteFunc(g.TypesByObject, func(_ string, t TypeI) bool {
+		return fromPkg(t)
+	})
+	g.Fields = slices.DeleteFunc(g.Fields, func(f FieldI) bool {
+		return fromPkg(f)
+	})
+	for id, funcs := range g.Funcs {
+		funcs = slices.DeleteFunc(funcs, func(f FuncI) bool {
+			return fromPkg(f)
+		})
+		if len(funcs) == 0 {
+			delete(g.Funcs, id)
+		} else {
+			g.Funcs[id] = funcs
+		}
+	}
+
+	for _, t := range g.Types {
+		if et, ok := t.(*Type); ok {
+			et.Subclasses = slices.DeleteFunc(et.Subclasses, func(s TypeI) bool {
+				return fromPkg(s)
+			})
+		}
+	}
 }
 
 var reportedTypes map[string]struct{} = map[string]struct{}{}
@@ -281,7 +623,7 @@ func (g *GeneratorBaseT) Prepare() {
 		if err != nil {
 			if _, ok := reportedTypes[f.GetTypeName()]; !ok {
 				reportedTypes[f.GetTypeName()] = struct{}{}
-				fmt.Printf("Error: %v\n", err)
+				g.Warnf(f, "%v", err)
 			}
 		}
 	}
@@ -294,7 +636,7 @@ func (g *GeneratorBaseT) Prepare() {
 
 		err := tb.Prepare(g.G)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			g.Errorf(t, "%v", err)
 		}
 
 		for base := range t.BasesSeq() {
@@ -0,0 +1,199 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/multi"
+)
+
+// EdgeKind tells which relation between types a TypeLine stands for.
+type EdgeKind int
+
+const (
+	EdgeBase     EdgeKind = iota // type embeds base type
+	EdgeExtends                  // type extends archetype
+	EdgeSubclass                 // base type has subclass not related back by base or extends
+	EdgeField                    // type has field of other type
+)
+
+func (k EdgeKind) String() string {
+	switch k {
+	case EdgeBase:
+		return "base"
+	case EdgeExtends:
+		return "extends"
+	case EdgeSubclass:
+		return "subclass"
+	case EdgeField:
+		return "field"
+	}
+
+	return fmt.Sprintf("edge(%d)", int(k))
+}
+
+// TypeNode is a type in TypeGraph.
+type TypeNode struct {
+	UID  int64
+	Type TypeI
+}
+
+func (n *TypeNode) ID() int64 {
+	return n.UID
+}
+
+// DOTID returns TypeKey of node type, so types of packages with the same
+// name stay apart. Nodes are labeled with GetFullName.
+func (n *TypeNode) DOTID() string {
+	return TypeKey(n.Type.GetPackage(), n.Type.GetName())
+}
+
+// TypeLine is a relation between two types in TypeGraph.
+type TypeLine struct {
+	F, T *TypeNode
+	UID  int64
+	Kind EdgeKind
+	Name string // field name of base and field relations
+}
+
+func (l TypeLine) From() graph.Node {
+	return l.F
+}
+
+func (l TypeLine) To() graph.Node {
+	return l.T
+}
+
+func (l TypeLine) ReversedLine() graph.Line {
+	l.F, l.T = l.T, l.F
+	return l
+}
+
+func (l TypeLine) ID() int64 {
+	return l.UID
+}
+
+// TypeGraph is a class diagram of types. Two types can be related in more
+// than one way, so it is a multigraph.
+type TypeGraph struct {
+	*multi.DirectedGraph
+
+	nodes map[TypeI]*TypeNode
+}
+
+func NewTypeGraph() *TypeGraph {
+	return &TypeGraph{
+		DirectedGraph: multi.NewDirectedGraph(),
+		nodes:         map[TypeI]*TypeNode{},
+	}
+}
+
+// AddType adds node of t unless graph has it already.
+func (g *TypeGraph) AddType(t TypeI) *TypeNode {
+	if n, ok := g.nodes[t]; ok {
+		return n
+	}
+
+	n := &TypeNode{
+		UID:  g.NewNode().ID(),
+		Type: t,
+	}
+	g.AddNode(n)
+	g.nodes[t] = n
+	return n
+}
+
+// TypeNode returns node of t.
+func (g *TypeGraph) TypeNode(t TypeI) (*TypeNode, bool) {
+	n, ok := g.nodes[t]
+	return n, ok
+}
+
+// AddRelation adds line of kind from type to type, both must be in graph.
+func (g *TypeGraph) AddRelation(from, to TypeI, kind EdgeKind, name string) {
+	f, ok := g.nodes[from]
+	if !ok {
+		return
+	}
+	t, ok := g.nodes[to]
+	if !ok {
+		return
+	}
+
+	g.SetLine(TypeLine{
+		F:    f,
+		T:    t,
+		UID:  g.NewLine(f, t).ID(),
+		Kind: kind,
+		Name: name,
+	})
+}
+
+// HasRelation reports whether graph has line of kind from type to type.
+func (g *TypeGraph) HasRelation(from, to TypeI, kind EdgeKind) bool {
+	f, ok := g.nodes[from]
+	if !ok {
+		return false
+	}
+	t, ok := g.nodes[to]
+	if !ok {
+		return false
+	}
+
+	lines := g.Lines(f.ID(), t.ID())
+	for lines.Next() {
+		if l, ok := lines.Line().(TypeLine); ok && l.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Graph returns class diagram of inspected types. Types are related by
+// embedded bases, extended archetypes, subclasses and fields. Subclass
+// relation is only added when subclass is not related back to its base.
+func (g *GeneratorBaseT) Graph() graph.Graph {
+	tg := NewTypeGraph()
+
+	names := slices.Sorted(maps.Keys(g.Types))
+	for _, name := range names {
+		tg.AddType(g.Types[name])
+	}
+
+	for _, name := range names {
+		t := g.Types[name]
+		for f := range t.BasesSeq() {
+			if bt := f.GetType(); bt != nil {
+				tg.AddRelation(t, bt, EdgeBase, f.GetName())
+			}
+		}
+		for f := range t.FieldsSeq() {
+			if ft := f.GetType(); ft != nil {
+				tg.AddRelation(t, ft, EdgeField, f.GetName())
+			}
+		}
+		if et, ok := t.(*Type); ok {
+			for _, xt := range et.Extends {
+				tg.AddRelation(t, xt, EdgeExtends, "")
+			}
+		}
+	}
+
+	for _, name := range names {
+		et, ok := g.Types[name].(*Type)
+		if !ok {
+			continue
+		}
+
+		for _, s := range et.Subclasses {
+			if tg.HasRelation(s, et, EdgeBase) || tg.HasRelation(s, et, EdgeExtends) {
+				continue
+			}
+			tg.AddRelation(et, s, EdgeSubclass, "")
+		}
+	}
+
+	return tg
+}
@@ -0,0 +1,22 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashBytes returns hex encoded sha256 hash of data.
+func HashBytes(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// HashStrings returns hex encoded sha256 hash of all parts.
+func HashStrings(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,70 @@
+package core
+
+import "go/types"
+
+// ObjectKey returns package path qualified name of obj. Unlike obj itself
+// key stays the same when package is loaded again.
+func ObjectKey(obj types.Object) string {
+	if obj.Pkg() == nil {
+		return obj.Name()
+	}
+
+	return obj.Pkg().Path() + "." + obj.Name()
+}
+
+// TypeKey returns package path qualified name of type declared in pkg, the
+// same as ObjectKey of its declaration. Package name is used for packages
+// without path.
+func TypeKey(pkg *Package, name string) string {
+	if pkg == nil {
+		return name
+	}
+	if pkg.Pkg != nil && pkg.Pkg.PkgPath != "" {
+		return pkg.Pkg.PkgPath + "." + name
+	}
+
+	return pkg.Name + "." + name
+}
+
+// NamedObject returns declaration of named type t refers to. Pointers,
+// slices, arrays and aliases are followed, generic instantiations resolve to
+// their generic type.
+func NamedObject(t types.Type) types.Object {
+	if nt, ok := types.Unalias(ElemType(t)).(*types.Named); ok {
+		return nt.Origin().Obj()
+	}
+
+	return nil
+}
+
+// TypeArgs returns type arguments of generic type instantiation t refers to.
+func TypeArgs(t types.Type) []types.Type {
+	nt, ok := types.Unalias(ElemType(t)).(*types.Named)
+	if !ok || nt.TypeArgs().Len() == 0 {
+		return nil
+	}
+
+	args := make([]types.Type, 0, nt.TypeArgs().Len())
+	for a := range nt.TypeArgs().Types() {
+		args = append(args, a)
+	}
+	return args
+}
+
+// ElemType returns t with pointers, slices and arrays stripped.
+func ElemType(t types.Type) types.Type {
+	for t != nil {
+		switch tt := types.Unalias(t).(type) {
+		case *types.Pointer:
+			t = tt.Elem()
+		case *types.Slice:
+			t = tt.Elem()
+		case *types.Array:
+			t = tt.Elem()
+		default:
+			return t
+		}
+	}
+
+	return nil
+}
@@ -3,9 +3,10 @@ package core
 import (
 	"go/ast"
 	"go/types"
+	"maps"
 	"path"
+	"slices"
 	"strings"
-	"time"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -20,7 +21,10 @@ type Package struct {
 	Name          string `hash:""`
 	Defs          map[*ast.Ident]types.Object
 	Files         []*File
-	ModTime       time.Time
+	Hash          string // Content hash of package files.
+	BuildTag      string // Build tag variant package was loaded with, empty for default build.
+	Constraint    string // Build constraint for generated code, empty for none.
+	Test          bool   // Package holds test files only, in-package or external _test package.
 	Imports       []Import
 	ImportedPkgs  map[string]*Package // Package imported by Pkg
 	ImportsByName map[string]int
@@ -29,6 +33,8 @@ type Package struct {
 	Types  map[string]TypeI
 	Fields []FieldI
 	Funcs  map[string][]FuncI
+
+	inputHash string
 }
 
 func MakePackage(pkg *packages.Package) Package {
@@ -50,6 +56,19 @@ func NewPackage(pkg *packages.Package) *Package {
 	return &p
 }
 
+// Same reports whether p and pkg are the same Go package. Test files of a
+// package are kept in separate Package which is still the same Go package.
+func (p *Package) Same(pkg *Package) bool {
+	if p == pkg {
+		return true
+	}
+	if p == nil || pkg == nil || p.Pkg == nil || pkg.Pkg == nil {
+		return false
+	}
+
+	return p.Pkg.PkgPath == pkg.Pkg.PkgPath
+}
+
 func (p *Package) Above(pkg *Package) bool {
 	return strings.HasPrefix(pkg.Pkg.PkgPath, p.Pkg.PkgPath)
 }
@@ -75,3 +94,29 @@ func (p *Package) HasImport(pkg *Package) bool {
 	_, ok := p.ImportsByPath[pkg.Pkg.PkgPath]
 	return ok
 }
+
+// LinkImports fills ImportedPkgs with packages from pkgs imported by p.
+func (p *Package) LinkImports(pkgs map[string]*Package) {
+	p.ImportedPkgs = map[string]*Package{}
+	for _, imp := range p.Imports {
+		if ipkg, ok := pkgs[imp.Path]; ok {
+			p.ImportedPkgs[imp.Path] = ipkg
+		}
+	}
+	p.inputHash = ""
+}
+
+// InputHash returns hash of package files and all local packages it imports.
+func (p *Package) InputHash() string {
+	if p.inputHash != "" {
+		return p.inputHash
+	}
+
+	parts := []string{p.Hash}
+	for _, path := range slices.Sorted(maps.Keys(p.ImportedPkgs)) {
+		parts = append(parts, path, p.ImportedPkgs[path].InputHash())
+	}
+	p.inputHash = HashStrings(parts...)
+
+	return p.inputHash
+}
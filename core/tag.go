@@ -1,6 +1,7 @@
 package core
 
 import (
+	"encoding/json"
 	"fmt"
 	"go/ast"
 	"maps"
@@ -111,3 +112,11 @@ func (t Tag) GetObject(name string) (Tag, bool) {
 
 	return Tag{}, false
 }
+
+func (t Tag) MarshalYAML() (interface{}, error) {
+	return t.Data, nil
+}
+
+func (t Tag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Data)
+}
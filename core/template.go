@@ -0,0 +1,172 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// TemplateGenerator is a generator which renders text/template files against
+// the package model. Every file matched when the generator is made is
+// executed in name order and outputs are concatenated, files which only
+// {{define}} templates render nothing and serve as helpers.
+type TemplateGenerator struct {
+	GeneratorBaseT
+
+	tmpl     *template.Template
+	files    []string
+	prepared bool
+}
+
+var _ Generator = (*TemplateGenerator)(nil)
+
+// TemplateData is the model templates are executed with.
+type TemplateData struct {
+	Pkg    *Package
+	Types  []TypeI  // Types declared in Pkg, sorted by name.
+	Fields []FieldI // Fields of Types.
+	Funcs  []FuncI  // Funcs declared in Pkg, methods included.
+}
+
+// NewTemplateGenerator makes a generator from templates in fsys matching
+// patterns. Use os.DirFS for templates on disk or an embed.FS for
+// templates built into the generator binary.
+func NewTemplateGenerator(flag string, fsys fs.FS, patterns []string, tags ...string) (*TemplateGenerator, error) {
+	g := &TemplateGenerator{
+		GeneratorBaseT: MakeGeneratorB(flag, tags...),
+	}
+	g.G = g
+
+	for _, p := range patterns {
+		matches, err := fs.Glob(fsys, p)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("pattern %s matches no templates", p)
+		}
+		for _, m := range matches {
+			g.files = append(g.files, path.Base(m))
+		}
+	}
+	slices.Sort(g.files)
+	g.files = slices.Compact(g.files)
+
+	tmpl, err := template.New(flag).Funcs(g.FuncMap()).ParseFS(fsys, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	g.tmpl = tmpl
+
+	return g, nil
+}
+
+// FuncMap returns functions available in templates.
+//
+//	localTypeName   type name as used in generated package
+//	typeImportName  package qualifier of type with a dot, empty for local types
+//	fieldTypeName   field type name with type arguments
+//	declArguments   function arguments as declared
+//	callArguments   function arguments to pass in a call
+//	declResults     function results as declared
+//	funcs           methods of type
+//	fields, bases   fields and embedded bases of type
+//	tag             string tag field of token, empty if not set
+//	hasTag          whether token tag has a field
+//	tagObject       nested tag object of token tag
+//	lower, upper    strings.ToLower and strings.ToUpper
+func (g *TemplateGenerator) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"localTypeName":  func(t TypeI) string { return g.LocalTypeName(t) },
+		"typeImportName": g.TypeImportName,
+		"fieldTypeName":  g.FieldTypeName,
+		"declArguments":  func(f FuncI) string { return templateFunc(f).DeclArguments() },
+		"callArguments":  func(f FuncI) string { return templateFunc(f).CallArguments() },
+		"declResults":    func(f FuncI) string { return templateFunc(f).DeclResults() },
+		"funcs":          g.GetFuncs,
+		"fields":         func(t TypeI) []FieldI { return slices.Collect(t.FieldsSeq()) },
+		"bases":          func(t TypeI) []FieldI { return slices.Collect(t.BasesSeq()) },
+		"tag": func(t TokenI, name string) string {
+			v, _ := t.GetTag().GetField(name)
+			return v
+		},
+		"hasTag": func(t TokenI, name string) bool {
+			return t.GetTag().HasField(name)
+		},
+		"tagObject": func(t TokenI, name string) Tag {
+			v, _ := t.GetTag().GetObject(name)
+			return v
+		},
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+	}
+}
+
+func templateFunc(f FuncI) *Func {
+	if ef, ok := f.(*Func); ok {
+		return ef
+	}
+
+	return &Func{}
+}
+
+// Data returns template model of pkg. Types and funcs declared in test
+// files of the package belong to its test Package and are not included.
+func (g *TemplateGenerator) Data(pkg *Package) TemplateData {
+	d := TemplateData{Pkg: pkg}
+
+	for _, name := range slices.Sorted(maps.Keys(g.Types)) {
+		t := g.Types[name]
+		if t.GetPackage() != pkg {
+			continue
+		}
+
+		d.Types = append(d.Types, t)
+		d.Fields = slices.AppendSeq(d.Fields, t.FieldsSeq())
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(g.Funcs)) {
+		for _, f := range g.Funcs[name] {
+			if f.GetPackage() == pkg {
+				d.Funcs = append(d.Funcs, f)
+			}
+		}
+	}
+
+	return d
+}
+
+// Invalidate forgets pkg, model is prepared again on next Generate.
+func (g *TemplateGenerator) Invalidate(pkg *Package) {
+	g.GeneratorBaseT.Invalidate(pkg)
+	g.prepared = false
+}
+
+// Prepare prepares model of inspected packages. Generate calls it when
+// model was not prepared yet or a package was invalidated since.
+func (g *TemplateGenerator) Prepare() {
+	g.GeneratorBaseT.Prepare()
+	g.prepared = true
+}
+
+// Generate prepares model if needed and executes templates for pkg.
+func (g *TemplateGenerator) Generate(pkg *Package) (buf bytes.Buffer, d Diagnostics, err error) {
+	g.Pkg = pkg
+	if !g.prepared {
+		g.Prepare()
+	}
+
+	data := g.Data(pkg)
+	for _, name := range g.files {
+		if err = g.tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+			return buf, g.TakeDiagnostics(), err
+		}
+	}
+
+	return buf, g.TakeDiagnostics(), nil
+}
@@ -1,36 +1,58 @@
 package core
 
+import (
+	"fmt"
+	"go/token"
+	"path/filepath"
+)
+
 type TokenI interface {
 	GetName() string
 	GetFullName() string
 	GetTag() Tag
 	GetPackage() *Package
 	SetPackage(pkg *Package)
+	GetPosition() token.Position
 }
 
 type TokenM interface {
+	SetName(name string)
 	SetTag(tag Tag)
+	SetPos(pos token.Pos)
 }
 
 type Token struct {
 	Name    string   `hash:""`
 	Tag     Tag      `hash:""`
 	Package *Package `hash:""`
+	Pos     token.Pos
 }
 
+// TokenDto is exported part of a token, see PackageDto.
 type TokenDto struct {
-	Name    string  `yaml:""`
-	Tag     TagData `yaml:""`
-	Package string  `yaml:""`
+	Name     string  `yaml:"name" json:"name"`
+	Tag      TagData `yaml:"tag,omitempty" json:"tag,omitempty"`
+	Package  string  `yaml:"package,omitempty" json:"package,omitempty"`
+	Position string  `yaml:"position,omitempty" json:"position,omitempty"` // file:line:col, file name is relative to package directory
+}
+
+func MakeTokenDto(t TokenI) TokenDto {
+	dto := TokenDto{
+		Name: t.GetName(),
+		Tag:  t.GetTag().Data,
+	}
+	if pkg := t.GetPackage(); pkg != nil {
+		dto.Package = pkg.Name
+	}
+	if pos := t.GetPosition(); pos.IsValid() {
+		dto.Position = fmt.Sprintf("%s:%d:%d", filepath.Base(pos.Filename), pos.Line, pos.Column)
+	}
+
+	return dto
 }
 
 func (t Token) MarshalYAML() (interface{}, error) {
-	// Custom marshaling logic
-	return TokenDto{
-		Name:    t.Name,
-		Tag:     t.Tag.Data,
-		Package: t.Package.Name,
-	}, nil
+	return MakeTokenDto(&t), nil
 }
 
 /*
@@ -68,6 +90,14 @@ func (t Token) GetTag() Tag {
 	return t.Tag
 }
 
+func (t *Token) SetName(name string) {
+	t.Name = name
+}
+
+func (t *Token) SetPos(pos token.Pos) {
+	t.Pos = pos
+}
+
 func (t *Token) SetTag(tag Tag) {
 	t.Tag = tag
 }
@@ -79,3 +109,11 @@ func (t Token) GetPackage() *Package {
 func (t *Token) SetPackage(pkg *Package) {
 	t.Package = pkg
 }
+
+func (t Token) GetPosition() token.Position {
+	if t.Package == nil || t.Package.Pkg == nil || t.Package.Pkg.Fset == nil || !t.Pos.IsValid() {
+		return token.Position{}
+	}
+
+	return t.Package.Pkg.Fset.Position(t.Pos)
+}
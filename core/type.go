@@ -1,15 +1,19 @@
 package core
 
 import (
+	"fmt"
 	"iter"
 	"maps"
 	"slices"
+	"strings"
 )
 
 type TypeI interface {
 	TokenI
 
 	IsZero() bool
+	GetKind() TypeKind
+	GetTypeParams() []TypeParam
 
 	BasesSeq() iter.Seq[FieldI]
 	FieldsSeq() iter.Seq[FieldI]
@@ -25,8 +29,48 @@ type TypeBuilder interface {
 	AddSubclass(s TypeI)
 }
 
+// TypeKind tells which kind of type declaration Type was built from.
+type TypeKind int
+
+const (
+	KindStruct    TypeKind = iota // type T struct { ... }
+	KindInterface                 // type T interface { ... }
+	KindNamed                     // type T int, type T []int and other named types
+	KindFunc                      // type T func(...)
+	KindAlias                     // type T = U
+)
+
+func (k TypeKind) String() string {
+	switch k {
+	case KindStruct:
+		return "struct"
+	case KindInterface:
+		return "interface"
+	case KindNamed:
+		return "named"
+	case KindFunc:
+		return "func"
+	case KindAlias:
+		return "alias"
+	}
+
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// TypeParam is a type parameter of generic type.
+type TypeParam struct {
+	Name       string
+	Constraint string
+}
+
 type Type struct {
 	Token      `yaml:",inline"`
+	Kind       TypeKind         `yaml:""`
+	Underlying string           `yaml:""` // underlying type of named and func types, target of alias, inline struct declaration
+	Inline     bool             `yaml:""` // anonymous struct declared as field type
+	Signature  *Func            `yaml:""` // signature of func type
+	Methods    []FuncI          `yaml:""` // method set of interface
+	TypeParams []TypeParam      `yaml:""`
 	Subclasses []TypeI          `yaml:""`
 	BaseFields []FieldI         `yaml:""` // base types go lang way (deprecated for archetype)
 	Extends    []TypeI          `yaml:""` // extends for archetypes
@@ -60,6 +104,42 @@ func (t Type) IsZero() bool {
 	return t.isZero && len(t.BaseFields) == 0
 }
 
+func (t Type) GetKind() TypeKind {
+	return t.Kind
+}
+
+func (t Type) GetTypeParams() []TypeParam {
+	return t.TypeParams
+}
+
+// DeclTypeParams returns type parameters list for type declaration, like
+// "[K comparable, V any]", or empty string for non generic type.
+func (t Type) DeclTypeParams() string {
+	if len(t.TypeParams) == 0 {
+		return ""
+	}
+
+	params := make([]string, 0, len(t.TypeParams))
+	for _, p := range t.TypeParams {
+		params = append(params, p.Name+" "+p.Constraint)
+	}
+	return "[" + strings.Join(params, ", ") + "]"
+}
+
+// CallTypeParams returns type parameters list for type usage, like "[K, V]",
+// or empty string for non generic type.
+func (t Type) CallTypeParams() string {
+	if len(t.TypeParams) == 0 {
+		return ""
+	}
+
+	params := make([]string, 0, len(t.TypeParams))
+	for _, p := range t.TypeParams {
+		params = append(params, p.Name)
+	}
+	return "[" + strings.Join(params, ", ") + "]"
+}
+
 //func (t Type) GetName() string {
 //	return t.Name
 //}
@@ -119,7 +199,13 @@ func (t Type) HasFunction(name string) bool {
 
 func (t *Type) Prepare(tf TypeFactory) error {
 	t.Funcs = map[string]FuncI{}
+	for _, f := range t.Methods {
+		t.Funcs[f.GetName()] = f
+	}
 	for _, f := range tf.GetFuncs(t) {
+		if ef, ok := CastFunc(f); ok && ef.Receiver != nil {
+			ef.Receiver.Type = t
+		}
 		t.Funcs[f.GetName()] = f
 	}
 	return nil
@@ -0,0 +1,83 @@
+package gogen
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+)
+
+// RunError is an error which happened while processing a package.
+type RunError struct {
+	Pkg       string // Package path.
+	Generator string // Generator flag, empty for package level errors.
+	Err       error
+}
+
+func (e *RunError) Error() string {
+	if e.Generator == "" {
+		return fmt.Sprintf("%s: %v", e.Pkg, e.Err)
+	}
+
+	return fmt.Sprintf("%s [%s]: %v", e.Pkg, e.Generator, e.Err)
+}
+
+func (e *RunError) Unwrap() error {
+	return e.Err
+}
+
+// RunErrors collects errors from all packages and generators of a run.
+type RunErrors struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (r *RunErrors) Add(pkg, generator string, err error) {
+	if err == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, &RunError{
+		Pkg:       pkg,
+		Generator: generator,
+		Err:       err,
+	})
+}
+
+func (r *RunErrors) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.errs)
+}
+
+// Err returns all collected errors joined or nil if there were none.
+func (r *RunErrors) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return errors.Join(r.errs...)
+}
+
+// Errors returns collected errors.
+func (r *RunErrors) Errors() []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.errs)
+}
+
+// Summary logs every collected error followed by total count.
+func (r *RunErrors) Summary() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.errs) == 0 {
+		return
+	}
+
+	for _, err := range r.errs {
+		log.Printf("error: %v", err)
+	}
+	log.Printf("%d errors", len(r.errs))
+}
@@ -0,0 +1,77 @@
+package gogen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+
+	"github.com/igadmg/gogen/core"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/imports"
+)
+
+// formatSource formats generated code and fixes its imports in process.
+// Imports of pkg are added first for every package name used in code, so
+// usually nothing has to be searched in module cache.
+func formatSource(fileName string, code []byte, pkg *core.Package) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, code, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	if pkg != nil {
+		addImportHints(fset, file, pkg)
+	}
+
+	var src bytes.Buffer
+	if err := format.Node(&src, fset, file); err != nil {
+		return nil, err
+	}
+
+	return imports.Process(fileName, src.Bytes(), nil)
+}
+
+// addImportHints adds imports from pkg which names are used as qualifiers
+// in file. Unused ones are removed later by imports.Process.
+func addImportHints(fset *token.FileSet, file *ast.File, pkg *core.Package) {
+	used := map[string]struct{}{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = struct{}{}
+			}
+		}
+		return true
+	})
+
+	self := ""
+	names := map[string]string{}
+	if pkg.Pkg != nil {
+		self = pkg.Pkg.PkgPath
+		if pkg.Pkg.Types != nil {
+			for _, ip := range pkg.Pkg.Types.Imports() {
+				names[ip.Path()] = ip.Name()
+			}
+		}
+	}
+
+	for _, imp := range pkg.Imports {
+		if imp.Name == "_" || imp.Name == "." || imp.Path == self {
+			continue
+		}
+
+		if name, ok := names[imp.Path]; ok {
+			if _, ok := used[name]; ok {
+				astutil.AddImport(fset, file, imp.Path)
+				continue
+			}
+		}
+
+		if _, ok := used[imp.Name]; ok {
+			astutil.AddNamedImport(fset, file, imp.Name, imp.Path)
+		}
+	}
+}
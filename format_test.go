@@ -0,0 +1,65 @@
+package gogen
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/igadmg/gogen/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestFormatSource(t *testing.T) {
+	tpkg := types.NewPackage("example.com/world", "world")
+	tpkg.SetImports([]*types.Package{types.NewPackage("strings", "strings")})
+
+	pkg := &core.Package{
+		Pkg: &packages.Package{
+			PkgPath: "example.com/world",
+			Types:   tpkg,
+		},
+		Imports: []core.Import{
+			{Name: "strings", Path: "strings"},
+			{Name: "str", Path: "strconv"},
+			{Name: "p", Path: "path"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		code    string
+		imports []string
+		unused  []string
+	}{
+		{
+			name:    "named import",
+			code:    "func Itoa(n int) string { return str.Itoa(n) }",
+			imports: []string{`str "strconv"`},
+		},
+		{
+			name:    "import used only by generated code",
+			code:    `func Upper(s string) string { return strings.ToUpper(s) }`,
+			imports: []string{`"strings"`},
+		},
+		{
+			name:   "unused hint",
+			code:   "type Player struct{ name string }\nfunc (p Player) Name() string { return p.name }",
+			unused: []string{`"path"`, `"strings"`, `"strconv"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := formatSource("0.gen_test.go", []byte("package world\n"+tt.code), pkg)
+			require.NoError(t, err)
+
+			for _, imp := range tt.imports {
+				assert.Contains(t, string(src), imp)
+			}
+			for _, imp := range tt.unused {
+				assert.NotContains(t, string(src), imp)
+			}
+		})
+	}
+}
@@ -10,7 +10,6 @@ replace (
 
 require (
 	deedles.dev/xiter v0.2.1
-	github.com/igadmg/goecs v0.0.0-20250605212039-e73961886726
 	github.com/igadmg/goex v0.0.0-20250511161240-125903d9a179
 	github.com/stretchr/testify v1.10.0
 	golang.org/x/tools v0.34.0
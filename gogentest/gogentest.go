@@ -0,0 +1,83 @@
+// Package gogentest runs generators on testdata packages and compares their
+// outputs with golden files.
+//
+// Case is a package in testdata/<case> directory of the test. Every output
+// is compared with file of the same name and .golden suffix next to the
+// package sources, like testdata/basic/0.gen_ecs.go.golden. Run tests with
+// -update flag to write current outputs to golden files.
+package gogentest
+
+import (
+	"bytes"
+	"flag"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/igadmg/gogen"
+	"github.com/igadmg/gogen/core"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// Generate loads testdata/<name> package, inspects and prepares it and
+// returns outputs of g by output file name. Test files of the package are
+// loaded only if -tests flag is set with gogen.Setup.
+func Generate(t testing.TB, g core.Generator, name string) map[string][]byte {
+	t.Helper()
+
+	errs := &gogen.RunErrors{}
+	ppkgs := gogen.LoadPackages([]string{"./" + filepath.Join("testdata", name)}, "", errs)
+	if err := errs.Err(); err != nil {
+		t.Fatalf("loading %s: %v", name, err)
+	}
+
+	gogen.Inspect(ppkgs, g)
+	gogen.LinkPackages(ppkgs)
+	g.Prepare()
+
+	outputs := map[string][]byte{}
+	for _, path := range slices.Sorted(maps.Keys(ppkgs)) {
+		pkg := ppkgs[path]
+		src, diags, err := gogen.GenerateSource(g, pkg)
+		for _, d := range diags {
+			t.Log(d)
+		}
+		if err != nil {
+			t.Fatalf("generating %s: %v", path, err)
+		}
+
+		outputs[gogen.OutputName(pkg, g, ".go")] = src
+	}
+
+	return outputs
+}
+
+// Run generates testdata/<name> package with g and compares outputs with
+// golden files. Missing golden file is an error unless -update is set.
+func Run(t testing.TB, g core.Generator, name string) {
+	t.Helper()
+
+	for fileName, src := range Generate(t, g, name) {
+		goldenName := fileName + ".golden"
+
+		if *update {
+			if err := os.WriteFile(goldenName, src, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		golden, err := os.ReadFile(goldenName)
+		if err != nil {
+			t.Errorf("%v, run test with -update to create it", err)
+			continue
+		}
+
+		if !bytes.Equal(src, golden) {
+			t.Errorf("%s differs from %s:\n%s", filepath.Base(fileName), filepath.Base(goldenName), src)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package gogentest
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/igadmg/gogen"
+	"github.com/igadmg/gogen/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateGenerator(t *testing.T) {
+	defer func(tags []string) { core.Tags = tags }(core.Tags)
+	core.Tags = append(core.Tags, "tmpl")
+
+	g, err := core.NewTemplateGenerator("tmpl", os.DirFS("testdata/templates"), []string{"*.tmpl"}, "tmpl")
+	require.NoError(t, err)
+
+	Run(t, g, "basic")
+}
+
+func TestTemplateGeneratorTests(t *testing.T) {
+	defer func(tags []string) { core.Tags = tags }(core.Tags)
+	core.Tags = append(core.Tags, "tmpl")
+
+	g, err := core.NewTemplateGenerator("tmpl", os.DirFS("testdata/templates"), []string{"*.tmpl"}, "tmpl")
+	require.NoError(t, err)
+
+	gogen.Setup(flag.NewFlagSet("gogen", flag.ContinueOnError), []string{"-tests"}, g)
+	t.Cleanup(func() { gogen.Setup(flag.NewFlagSet("gogen", flag.ContinueOnError), nil, g) })
+
+	outputs := Generate(t, g, "tests")
+	require.Len(t, outputs, 3, "package, in-package and external tests outputs")
+
+	Run(t, g, "tests")
+}
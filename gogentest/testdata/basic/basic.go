@@ -0,0 +1,11 @@
+package basic
+
+type Player struct {
+	Name  string `tmpl:"log: name"`
+	Score int
+}
+
+func (p *Player) Rename(name string) error {
+	p.Name = name
+	return nil
+}
@@ -0,0 +1,10 @@
+package tests
+
+type Player struct {
+	Name string `tmpl:"log: name"`
+}
+
+func (p *Player) Rename(name string) error {
+	p.Name = name
+	return nil
+}
@@ -0,0 +1,10 @@
+package tests
+
+type playerFixture struct {
+	Label string `tmpl:"log: label"`
+}
+
+func (p *Player) reset() error {
+	p.Name = ""
+	return nil
+}
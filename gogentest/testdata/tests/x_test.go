@@ -0,0 +1,7 @@
+package tests_test
+
+import "github.com/igadmg/gogen/gogentest/testdata/tests"
+
+type playerCase struct {
+	Player tests.Player `tmpl:"log: case"`
+}
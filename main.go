@@ -1,18 +1,20 @@
 package gogen
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"go/ast"
-	"go/format"
 	"go/parser"
 	"go/token"
 	"log"
 	"maps"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime/debug"
 	"slices"
 	"strings"
 	"sync"
@@ -21,19 +23,34 @@ import (
 	"deedles.dev/xiter"
 	"github.com/igadmg/goex/gx"
 	"github.com/igadmg/goex/pprofex"
-	"github.com/igadmg/goex/timeex"
 	"github.com/igadmg/gogen/core"
 	"golang.org/x/tools/go/packages"
-	"gonum.org/v1/gonum/graph/encoding/dot"
+	"gonum.org/v1/gonum/graph"
 )
 
 var (
 	profile_f       *bool
 	no_store_dot_f  *bool
+	diagram_f       *string
+	focus_f         *string
+	focus_depth_f   *int
 	no_store_yaml_f *bool
-	appModTime      time.Time
+	no_store_json_f *bool
+	schema_f        *bool
+	check_f         *bool
+	watch_f         *bool
+	watch_period_f  *time.Duration
+	tags_f          *string
+	build_flags_f   *string
+	tag_variants_f  *string
+	tests_f         *bool
+	appHash         string
+	flagSet         *flag.FlagSet // gogen and generator flags parsed by Setup
+	projectConfigs  *Configs      // nil when generators are chosen in command line only
 )
 
+const hashPrefix = "// gogen:hash "
+
 func Usage() {
 	fmt.Fprintf(os.Stderr, "Usage of gog:\n")
 	fmt.Fprintf(os.Stderr, "\tgog [flags]\n")
@@ -42,9 +59,47 @@ func Usage() {
 }
 
 func Execute(fg *flag.FlagSet, generators ...core.Generator) {
+	dir, generators := Setup(fg, os.Args[1:], generators...)
+
+	if *schema_f {
+		os.Stdout.Write(core.ModelSchema)
+		return
+	}
+
+	if *watch_f {
+		if *check_f {
+			log.Fatal("-check is not supported in watch mode")
+		}
+		if err := Watch(dir, *watch_period_f, generators...); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := Run(dir, generators...); err != nil {
+		os.Exit(1)
+	}
+}
+
+// Setup registers gogen and generator flags on fg, parses args and applies
+// project configuration. Returns package patterns to generate and
+// generators to run. Must be called before Run, Watch or Generate.
+func Setup(fg *flag.FlagSet, args []string, generators ...core.Generator) ([]string, []core.Generator) {
 	profile_f = fg.Bool("profile", false, "write cpu profile to `file`")
-	no_store_dot_f = fg.Bool("no_store_dot", true, "don't store dot file with class diagram")
+	no_store_dot_f = fg.Bool("no_store_dot", true, "don't store dot file with class diagram, unless -diagram is given")
+	diagram_f = fg.String("diagram", "", "comma separated list of class diagram formats to store: dot, mermaid, plantuml")
+	focus_f = fg.String("focus", "", "draw only types related to this type in class diagrams, type name or package.Type")
+	focus_depth_f = fg.Int("focus_depth", 1, "how many relations away from -focus type are drawn")
 	no_store_yaml_f = fg.Bool("no_store_yaml", true, "don't store yaml file with metadata")
+	no_store_json_f = fg.Bool("no_store_json", true, "don't store json file with metadata")
+	schema_f = fg.Bool("schema", false, "print JSON Schema of json metadata and exit")
+	check_f = fg.Bool("check", false, "don't write anything, fail if generated files are out of date")
+	watch_f = fg.Bool("watch", false, "keep running and regenerate code when sources change")
+	watch_period_f = fg.Duration("watch_period", 500*time.Millisecond, "how often sources are checked in watch mode")
+	tags_f = fg.String("tags", "", "comma separated list of build tags to load packages with")
+	build_flags_f = fg.String("build_flags", "", "additional space separated build flags to load packages with")
+	tests_f = fg.Bool("tests", false, "inspect test packages too and generate _test.go outputs for types declared in tests")
+	tag_variants_f = fg.String("tag_variants", "", "comma separated list of mutually exclusive build tags, packages which differ with a tag get separate constrained output")
 
 	flags := map[string]*bool{}
 	tags := map[string]struct{}{}
@@ -53,6 +108,10 @@ func Execute(fg *flag.FlagSet, generators ...core.Generator) {
 			tags[tag] = struct{}{}
 		}
 		flags[generator.Flag()] = fg.Bool(generator.Flag(), false, "generate "+generator.Flag()+" code")
+
+		if fr, ok := generator.(core.FlagsRegistrar); ok {
+			fr.RegisterFlags(fg, generator.Flag()+".")
+		}
 	}
 
 	core.Tags = slices.Collect(maps.Keys(tags))
@@ -60,25 +119,51 @@ func Execute(fg *flag.FlagSet, generators ...core.Generator) {
 	log.SetFlags(0)
 	log.SetPrefix("gogen: ")
 	fg.Usage = Usage
-	fg.Parse(os.Args[1:])
+	fg.Parse(args)
+	flagSet = fg
+
+	for _, format := range splitList(*diagram_f) {
+		if _, ok := diagramFormats[format]; !ok {
+			log.Fatalf("unknown diagram format %s", format)
+		}
+	}
+
+	projectConfigs = NewConfigs()
+	if err := projectConfigs.ApplyOptions(gx.Must(os.Getwd()), fg); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, tag := range splitList(*tag_variants_f) {
+		if err := checkVariantTag(tag); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	for _, g := range generators {
+		if fr, ok := g.(core.FlagsRegistrar); ok {
+			if err := fr.FlagsParsed(); err != nil {
+				log.Fatalf("%s: %v", g.Flag(), err)
+			}
+		}
+	}
+
+	fg.Visit(func(f *flag.Flag) {
+		if v, ok := flags[f.Name]; ok {
+			if projectConfigs.Generators == nil {
+				projectConfigs.Generators = map[string]bool{}
+			}
+			projectConfigs.Generators[f.Name] = *v
+		}
+	})
 
 	var dir []string
-	args := fg.Args()
-	if len(args) > 0 {
-		dir = args
+	if len(fg.Args()) > 0 {
+		dir = fg.Args()
 	} else {
 		dir = []string{gx.Must(os.Getwd())}
 	}
 
-	ex, err := os.Executable()
-	if err != nil {
-		panic(err)
-	}
-	pfs, err := os.Stat(ex)
-	if err != nil {
-		panic(err)
-	}
-	appModTime = pfs.ModTime()
+	appHash = appIdentity()
 
 	/*
 		// TODO(suzmue): accept other patterns for packages (directories, list of files, import paths, etc).
@@ -89,26 +174,224 @@ func Execute(fg *flag.FlagSet, generators ...core.Generator) {
 		}
 	*/
 
-	generators = slices.Collect(xiter.Filter(slices.Values(generators), func(g core.Generator) bool {
-		if f, ok := flags[g.Flag()]; !ok || !*f {
-			return false
-		}
+	// Without generators chosen in command line every generator is run and
+	// gogen.yaml files decide which packages it generates code for.
+	if projectConfigs.Generators != nil {
+		generators = slices.Collect(xiter.Filter(slices.Values(generators), func(g core.Generator) bool {
+			if f, ok := flags[g.Flag()]; !ok || !*f {
+				return false
+			}
 
-		return true
-	}))
+			return true
+		}))
+	}
 
-	Run(dir, generators...)
+	return dir, generators
 }
 
-func Run(pkgNames []string, generators ...core.Generator) {
+func Run(pkgNames []string, generators ...core.Generator) error {
 	if *profile_f {
 		defer gx.Must(pprofex.WriteCPUProfile("gogen"))()
 	}
 
-	cfg := &packages.Config{
-		Mode: packages.NeedName | packages.NeedFiles | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedSyntax,
-		// TODO: Need to think about constants in test files. Maybe write type_string_test.go
-		// in a separate pass? For later.
+	errs := &RunErrors{}
+	defer errs.Summary()
+
+	ppkgs := LoadPackages(pkgNames, "", errs)
+	if len(ppkgs) == 0 {
+		return errs.Err()
+	}
+
+	variants := loadVariants(pkgNames, ppkgs, errs)
+	if len(variants) > 0 {
+		for _, g := range generators {
+			if _, ok := g.(core.Invalidator); !ok {
+				errs.Add(strings.Join(pkgNames, " "), g.Flag(), fmt.Errorf("generator does not support tag variants"))
+				return errs.Err()
+			}
+		}
+	}
+
+	Inspect(ppkgs, generators...)
+	LinkPackages(ppkgs)
+	stale := Generate(ppkgs, errs, generators...)
+
+	inspected := ppkgs
+	for _, tag := range splitList(*tag_variants_f) {
+		vpkgs, ok := variants[tag]
+		if !ok {
+			continue
+		}
+
+		for _, pkg := range inspected {
+			for _, g := range generators {
+				g.(core.Invalidator).Invalidate(pkg)
+			}
+		}
+
+		Inspect(vpkgs, generators...)
+		LinkPackages(vpkgs)
+		inspected = vpkgs
+
+		changed := map[string]*core.Package{}
+		for path, pkg := range vpkgs {
+			if pkg.BuildTag != "" {
+				changed[path] = pkg
+			}
+		}
+		stale = append(stale, Generate(changed, errs, generators...)...)
+	}
+	stale = append(stale, removeVariantOutputs(ppkgs, variants, errs, generators...)...)
+
+	if len(stale) > 0 {
+		for _, name := range stale {
+			log.Printf("out of date: %s", name)
+		}
+		errs.Add(strings.Join(pkgNames, " "), "", fmt.Errorf("%d generated files are out of date", len(stale)))
+	}
+
+	return errs.Err()
+}
+
+// loadVariants loads packages once for every tag variant. Packages which
+// sources differ from default build are marked with variant build tag and
+// constraint, their default build counterparts get negated constraint.
+// Only variants with differing packages are returned.
+func loadVariants(pkgNames []string, ppkgs map[string]*core.Package, errs *RunErrors) map[string]map[string]*core.Package {
+	variants := map[string]map[string]*core.Package{}
+	for _, tag := range splitList(*tag_variants_f) {
+		vpkgs := LoadPackages(pkgNames, tag, errs)
+
+		differs := false
+		for path, vpkg := range vpkgs {
+			pkg, ok := ppkgs[path]
+			if ok && pkg.Hash == vpkg.Hash {
+				continue
+			}
+
+			differs = true
+			vpkg.BuildTag = tag
+			vpkg.Constraint = tag
+			if ok {
+				pkg.Constraint = andConstraint(pkg.Constraint, "!"+tag)
+			}
+		}
+
+		if differs {
+			variants[tag] = vpkgs
+		}
+	}
+
+	return variants
+}
+
+// removeVariantOutputs removes outputs of tag variants packages no longer
+// differ with. In check mode nothing is removed and names of such outputs
+// are returned instead.
+func removeVariantOutputs(ppkgs map[string]*core.Package, variants map[string]map[string]*core.Package, errs *RunErrors, generators ...core.Generator) (stale []string) {
+	for _, tag := range splitList(*tag_variants_f) {
+		for path, pkg := range ppkgs {
+			if vpkg, ok := variants[tag][path]; ok && vpkg.BuildTag != "" {
+				continue
+			}
+
+			for _, g := range generators {
+				name := outputName(pkg, g.Flag(), tag, ".go")
+				if _, err := os.Stat(name); err != nil {
+					continue
+				}
+
+				if *check_f {
+					stale = append(stale, name)
+					continue
+				}
+
+				log.Printf("Removing file %s", name)
+				if err := os.Remove(name); err != nil {
+					errs.Add(pkg.Pkg.PkgPath, g.Flag(), err)
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// fileNameConstraints are GOOS and GOARCH values, go tool treats file
+// names ending with _<value> as build constrained.
+var fileNameConstraints = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+	"illumos": true, "ios": true, "js": true, "linux": true, "nacl": true, "netbsd": true,
+	"openbsd": true, "plan9": true, "solaris": true, "wasip1": true, "windows": true, "zos": true,
+
+	"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true,
+	"arm64be": true, "loong64": true, "mips": true, "mipsle": true, "mips64": true, "mips64le": true,
+	"mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true, "ppc64le": true,
+	"riscv": true, "riscv64": true, "s390": true, "s390x": true, "sparc": true, "sparc64": true,
+	"wasm": true,
+}
+
+// checkVariantTag returns error if tag can't name variant output, which is
+// 0.gen_<flag>_<tag>.go.
+func checkVariantTag(tag string) error {
+	switch lt := strings.ToLower(tag); {
+	case fileNameConstraints[lt]:
+		return fmt.Errorf("tag variant %s is GOOS or GOARCH, its output name would be build constrained", tag)
+	case lt == "test":
+		return fmt.Errorf("tag variant %s would make output a test file", tag)
+	}
+
+	return nil
+}
+
+func andConstraint(a, b string) string {
+	if a == "" {
+		return b
+	}
+
+	return a + " && " + b
+}
+
+func splitList(s string) []string {
+	return slices.Collect(xiter.Filter(slices.Values(strings.Split(s, ",")), func(s string) bool {
+		return s != ""
+	}))
+}
+
+// buildFlags returns build flags for packages loading with optional extra
+// build tag.
+func buildFlags(tag string) []string {
+	flags := []string{}
+	if build_flags_f != nil {
+		flags = append(flags, strings.Fields(*build_flags_f)...)
+	}
+
+	tags := []string{}
+	if tags_f != nil {
+		tags = splitList(*tags_f)
+	}
+	if tag != "" {
+		tags = append(tags, tag)
+	}
+	if len(tags) > 0 {
+		flags = append(flags, "-tags="+strings.Join(tags, ","))
+	}
+
+	return flags
+}
+
+func packagesConfig(tag string) *packages.Config {
+	mode := packages.NeedName | packages.NeedFiles | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedSyntax
+
+	tests := tests_f != nil && *tests_f
+	if tests {
+		// Test binary packages can't be type checked from export data,
+		// so dependencies are loaded from source.
+		mode |= packages.NeedForTest | packages.NeedImports | packages.NeedDeps
+	}
+
+	return &packages.Config{
+		Mode: mode,
 		ParseFile: func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
 			fn := filepath.Base(filename)
 			if !strings.HasPrefix(fn, "0.gen") {
@@ -117,153 +400,495 @@ func Run(pkgNames []string, generators ...core.Generator) {
 				return parser.ParseFile(fset, filename, src, parser.PackageClauseOnly)
 			}
 		},
-		Tests: false,
-		//BuildFlags: []string{fmt.Sprintf("-tags=%s", strings.Join(tags, " "))},
+		Tests:      tests,
+		BuildFlags: buildFlags(tag),
 		//Logf: g.logf,
 	}
-	pkgs, err := packages.Load(cfg, pkgNames...)
+}
+
+// LoadPackages loads packages matching pkgNames with optional extra build
+// tag. Packages which failed to load are reported to errs and skipped.
+func LoadPackages(pkgNames []string, tag string, errs *RunErrors) map[string]*core.Package {
+	pkgs, err := packages.Load(packagesConfig(tag), pkgNames...)
 	if err != nil {
-		log.Fatal(err)
+		errs.Add(strings.Join(pkgNames, " "), "", err)
+		return nil
 	}
 	if len(pkgs) == 0 {
-		log.Fatalf("error: %d packages matching %v", len(pkgs), strings.Join(pkgNames, " "))
+		errs.Add(strings.Join(pkgNames, " "), "", fmt.Errorf("no packages matching"))
+		return nil
 	}
 
-	ppkgs := map[string]*core.Package{}
+	// Package with in-package tests is loaded twice, plain and test variant
+	// with test files. Test variant is used in place of plain one.
+	tested := map[string]bool{}
 	for _, pkg := range pkgs {
-		ppkgs[pkg.PkgPath] = func() *core.Package {
-			lpkg := core.NewPackage(pkg)
-
-			lpkg.ModTime = time.Time{}
-			for _, file := range pkg.Syntax {
-				fileName := pkg.Fset.Position(file.Package).Filename
-				if strings.HasPrefix(filepath.Base(fileName), "0.gen_") {
-					continue
-				}
+		if pkg.ForTest != "" && pkg.ForTest == pkg.PkgPath {
+			tested[pkg.PkgPath] = true
+		}
+	}
 
-				f := &core.File{
-					File:    file,
-					Pkg:     lpkg,
-					ModTime: timeex.EndOfTime,
-				}
+	ppkgs := map[string]*core.Package{}
+	for _, pkg := range pkgs {
+		if pkg.Name == "main" && strings.HasSuffix(pkg.PkgPath, ".test") {
+			continue // test binary main package
+		}
+		if pkg.ForTest == "" && tested[pkg.PkgPath] {
+			continue
+		}
 
-				fileInfo, err := os.Stat(fileName)
-				if err == nil {
-					f.ModTime = fileInfo.ModTime()
-				}
+		if err := packageError(pkg); err != nil {
+			errs.Add(pkg.PkgPath, "", err)
+			continue
+		}
 
-				if f.ModTime.Compare(lpkg.ModTime) > 0 {
-					lpkg.ModTime = f.ModTime
-				}
-				lpkg.Files = append(lpkg.Files, f)
+		// Test package left with generated files only is skipped, so its
+		// outputs are not kept up to date.
+		if pkg.ForTest == "" || pkg.ForTest != pkg.PkgPath {
+			lpkg := newPackage(pkg, func(string) bool { return true })
+			lpkg.Test = pkg.ForTest != ""
+			if !lpkg.Test || len(lpkg.Files) > 0 {
+				ppkgs[pkg.PkgPath] = lpkg
 			}
+			continue
+		}
 
-			return lpkg
-		}()
+		// Test files are split to own package, so types declared in tests
+		// go to _test.go output.
+		lpkg := newPackage(pkg, func(fileName string) bool {
+			return !strings.HasSuffix(fileName, "_test.go")
+		})
+		tpkg := newPackage(pkg, func(fileName string) bool {
+			return strings.HasSuffix(fileName, "_test.go")
+		})
+		tpkg.Test = true
+		tpkg.Hash = core.HashStrings(lpkg.Hash, tpkg.Hash)
+
+		ppkgs[pkg.PkgPath] = lpkg
+		if len(tpkg.Files) > 0 {
+			ppkgs[pkg.PkgPath+" [test]"] = tpkg
+		}
 	}
 
-	Inspect(ppkgs, generators...)
+	return ppkgs
+}
 
-	for _, pkg := range ppkgs {
-		pkg.ImportedPkgs = map[string]*core.Package{}
-		for _, imp := range pkg.Imports {
-			if ipkg, ok := ppkgs[imp.Path]; ok {
-				pkg.ImportedPkgs[imp.Path] = ipkg
+// newPackage returns package with files of pkg accepted by filter.
+func newPackage(pkg *packages.Package, filter func(fileName string) bool) *core.Package {
+	lpkg := core.NewPackage(pkg)
 
-				if ipkg.ModTime.Compare(pkg.ModTime) > 0 {
-					pkg.ModTime = ipkg.ModTime
-				}
-			}
+	hashes := []string{}
+	for _, file := range pkg.Syntax {
+		fileName := pkg.Fset.Position(file.Package).Filename
+		if strings.HasPrefix(filepath.Base(fileName), "0.gen_") || !filter(fileName) {
+			continue
+		}
+
+		f := &core.File{
+			File: file,
+			Pkg:  lpkg,
 		}
 
-		if appModTime.Compare(pkg.ModTime) > 0 {
-			pkg.ModTime = appModTime
+		src, err := os.ReadFile(fileName)
+		if err == nil {
+			f.Hash = core.HashBytes(src)
 		}
+
+		hashes = append(hashes, filepath.Base(fileName), f.Hash)
+		lpkg.Files = append(lpkg.Files, f)
 	}
+	lpkg.Hash = core.HashStrings(hashes...)
 
+	return lpkg
+}
+
+// LinkPackages connects every package with loaded packages it imports.
+func LinkPackages(ppkgs map[string]*core.Package) {
+	for _, pkg := range ppkgs {
+		pkg.LinkImports(ppkgs)
+	}
+}
+
+// Generate runs generators for every package and writes outputs which are out
+// of date. In check mode nothing is written and out of date file names are
+// returned instead.
+func Generate(ppkgs map[string]*core.Package, errs *RunErrors, generators ...core.Generator) (stale []string) {
 	var wg sync.WaitGroup
 
 	for _, pkg := range ppkgs {
+		if projectConfigs != nil {
+			if err := projectConfigs.Check(pkg); err != nil {
+				errs.Add(pkg.Pkg.PkgPath, "", err)
+				continue
+			}
+		}
+
 		for _, g := range generators {
-			func(g core.Generator, pkg *core.Package) {
-				baseName := "0.gen_" + g.Flag() + ".go"
-				outputName := filepath.Join(pkg.Pkg.Dir, strings.ToLower(baseName))
-				if fs, err := os.Stat(outputName); !errors.Is(err, os.ErrNotExist) {
-					if fs.ModTime().Compare(pkg.ModTime) > 0 {
-						return
-					}
+			if projectConfigs != nil {
+				if !projectConfigs.Enabled(pkg, g.Flag()) {
+					continue
 				}
 
-				code := g.Generate(pkg)
-
-				if !*no_store_dot_f {
-					wg.Add(1)
-					go func() {
-						defer wg.Done()
-						dg := g.Graph()
-
-						// Write the graph to DOT format
-						data, err := dot.Marshal(dg, "", "", "  ")
-						if err != nil {
-							log.Fatal(err)
-						}
-
-						baseName := "0.gen_" + g.Flag() + ".dot"
-						dotName := filepath.Join(pkg.Pkg.Dir, strings.ToLower(baseName))
-						log.Printf("Writing file %s", dotName)
-						err = os.WriteFile(dotName, data, 0644)
-						if err != nil {
-							log.Fatalf("writing output: %s", err)
-						}
-						log.Printf("Done file %s", dotName)
-					}()
+				if c, ok := g.(core.Configurable); ok {
+					if err := c.Configure(projectConfigs.Settings(pkg, g.Flag())); err != nil {
+						errs.Add(pkg.Pkg.PkgPath, g.Flag(), err)
+						continue
+					}
 				}
+			}
 
-				if !*no_store_yaml_f {
-					wg.Add(1)
-					go func() {
-						defer wg.Done()
-						baseName := "0.gen_" + g.Flag() + ".yaml"
-						dotName := filepath.Join(pkg.Pkg.Dir, strings.ToLower(baseName))
+			generate(g, pkg, errs, &wg, &stale)
+		}
+	}
 
-						// TODO: network comm here
-						//g.Yaml(dotName)
+	wg.Wait()
+	return
+}
 
-						log.Printf("Done file %s", dotName)
-					}()
-				}
+// OutputName returns name of file generated by g for pkg with extension ext.
+func OutputName(pkg *core.Package, g core.Generator, ext string) string {
+	return outputName(pkg, g.Flag(), pkg.BuildTag, ext)
+}
 
-				log.Printf("Formatting file %s", outputName)
-				src, err := format.Source(code.Bytes())
-				if err != nil {
-					// Should never happen, but can arise when developing this code.
-					// The user can compile the output to see the error.
-					log.Printf("warning: internal error: invalid Go generated: %s", err)
-					log.Printf("warning: compile the package to analyze the error")
+// outputName returns name of file generated by generator with flag for pkg
+// loaded with build tag.
+func outputName(pkg *core.Package, flag, tag, ext string) string {
+	baseName := "0.gen_" + flag
+	if tag != "" {
+		baseName += "_" + tag
+	}
+	if pkg.Test {
+		if strings.HasSuffix(pkg.Name, "_test") {
+			baseName += "_x"
+		}
+		baseName += "_test"
+	}
 
-					os.WriteFile(outputName, code.Bytes(), 0644)
-					return
-				}
+	return filepath.Join(pkg.Pkg.Dir, strings.ToLower(baseName+ext))
+}
+
+// GenerateSource returns formatted code g generates for pkg, without hash
+// header. It is used by generator tests, which compare outputs regardless
+// of gogen build.
+func GenerateSource(g core.Generator, pkg *core.Package) ([]byte, core.Diagnostics, error) {
+	code, diags, err := g.Generate(pkg)
+	if err == nil && diags.HasErrors() {
+		err = fmt.Errorf("generator reported errors")
+	}
+	if err != nil {
+		return nil, diags, err
+	}
+
+	var src bytes.Buffer
+	if pkg.Constraint != "" {
+		fmt.Fprintf(&src, "//go:build %s\n\n", pkg.Constraint)
+	}
+	src.Write(code.Bytes())
+
+	fsrc, err := formatSource(OutputName(pkg, g, ".go"), src.Bytes(), pkg)
+	if err != nil {
+		return nil, diags, fmt.Errorf("invalid Go generated: %w", err)
+	}
 
-				// Write to file.
-				log.Printf("Writing file %s", outputName)
-				err = os.WriteFile(outputName, src, 0644)
+	return fsrc, diags, nil
+}
+
+// outputHash returns hash of everything output of g for pkg depends on.
+func outputHash(g core.Generator, pkg *core.Package) string {
+	parts := []string{pkg.InputHash(), appHash, g.Flag(), pkg.Constraint}
+
+	if projectConfigs != nil {
+		// Map keys are marshaled sorted, so equal settings give equal hash.
+		settings := projectConfigs.Settings(pkg, g.Flag())
+		if data, err := json.Marshal(settings); err == nil {
+			parts = append(parts, string(data))
+		} else {
+			parts = append(parts, fmt.Sprint(settings))
+		}
+	}
+
+	if flagSet != nil {
+		// Generator flags are visited sorted by name.
+		prefix := g.Flag() + "."
+		flagSet.VisitAll(func(f *flag.Flag) {
+			if strings.HasPrefix(f.Name, prefix) {
+				parts = append(parts, f.Name, f.Value.String())
+			}
+		})
+	}
+
+	return core.HashStrings(parts...)
+}
+
+func generate(g core.Generator, pkg *core.Package, errs *RunErrors, wg *sync.WaitGroup, stale *[]string) {
+	defer func() {
+		if r := recover(); r != nil {
+			errs.Add(pkg.Pkg.PkgPath, g.Flag(), fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	outputName := OutputName(pkg, g, ".go")
+	hash := outputHash(g, pkg)
+	upToDate := !*check_f && readOutputHash(outputName) == hash
+	// Diagrams and model files have no hash, they are generated every run
+	// and written when content changed.
+	sideOutputs := !*check_f && (len(diagrams()) > 0 || !*no_store_yaml_f || !*no_store_json_f)
+	if upToDate && !sideOutputs {
+		return
+	}
+
+	var code bytes.Buffer
+	fmt.Fprintf(&code, "%s%s\n\n", hashPrefix, hash)
+	if pkg.Constraint != "" {
+		fmt.Fprintf(&code, "//go:build %s\n\n", pkg.Constraint)
+	}
+	gcode, diags, err := g.Generate(pkg)
+	for _, d := range diags {
+		fmt.Fprintln(os.Stderr, d)
+	}
+	if err == nil && diags.HasErrors() {
+		err = fmt.Errorf("generator reported errors")
+	}
+	if err != nil {
+		errs.Add(pkg.Pkg.PkgPath, g.Flag(), err)
+		return
+	}
+	code.Write(gcode.Bytes())
+
+	if *check_f {
+		src, err := formatSource(outputName, code.Bytes(), pkg)
+		if err != nil {
+			errs.Add(pkg.Pkg.PkgPath, g.Flag(), fmt.Errorf("invalid Go generated: %w", err))
+			return
+		}
+
+		// Hash header depends on gogen build, only code is compared.
+		existing, _ := os.ReadFile(outputName)
+		if !bytes.Equal(withoutHash(src), withoutHash(existing)) {
+			*stale = append(*stale, outputName)
+		}
+		return
+	}
+
+	if formats := diagrams(); len(formats) > 0 {
+		// Graph is taken now, next package Generate may change types.
+		dg := focusGraph(g.Graph())
+
+		for _, format := range formats {
+			df := diagramFormats[format]
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				data, err := df.marshal(dg)
 				if err != nil {
-					log.Fatalf("writing output: %s", err)
+					errs.Add(pkg.Pkg.PkgPath, g.Flag(), fmt.Errorf("%s diagram: %w", format, err))
+					return
 				}
-				log.Printf("Formatting file %s", outputName)
 
-				lint := exec.Command("go", "run", "golang.org/x/tools/cmd/goimports", "-w", outputName)
-				if err := lint.Run(); err != nil {
-					log.Fatal(err)
+				if err := writeOutput(OutputName(pkg, g, df.ext), data); err != nil {
+					errs.Add(pkg.Pkg.PkgPath, g.Flag(), fmt.Errorf("writing output: %w", err))
 				}
+			}()
+		}
+	}
+
+	if !*no_store_yaml_f {
+		storeModel(g, pkg, ".yaml", g.Yaml, errs, wg)
+	}
 
-				log.Printf("Done file %s", outputName)
-			}(g, pkg)
+	if !*no_store_json_f {
+		storeModel(g, pkg, ".json", g.Json, errs, wg)
+	}
+
+	if upToDate {
+		return
+	}
+
+	log.Printf("Formatting file %s", outputName)
+	src, err := formatSource(outputName, code.Bytes(), pkg)
+	if err != nil {
+		// Should never happen, but can arise when developing this code.
+		// The user can compile the output to see the error.
+		errs.Add(pkg.Pkg.PkgPath, g.Flag(), fmt.Errorf("internal error: invalid Go generated: %w", err))
+		log.Printf("warning: compile the package to analyze the error")
+
+		// Written without hash, so output stays out of date.
+		if err := os.WriteFile(outputName, withoutHash(code.Bytes()), 0644); err != nil {
+			errs.Add(pkg.Pkg.PkgPath, g.Flag(), fmt.Errorf("writing output: %w", err))
 		}
+		return
 	}
 
-	wg.Wait()
+	// Write to file.
+	log.Printf("Writing file %s", outputName)
+	err = os.WriteFile(outputName, src, 0644)
+	if err != nil {
+		errs.Add(pkg.Pkg.PkgPath, g.Flag(), fmt.Errorf("writing output: %w", err))
+		return
+	}
+
+	log.Printf("Done file %s", outputName)
+}
+
+// diagramFormats are class diagram formats by -diagram flag value.
+var diagramFormats = map[string]struct {
+	ext     string
+	marshal func(graph.Graph) ([]byte, error)
+}{
+	"dot":      {".dot", core.MarshalDot},
+	"mermaid":  {".mmd", core.MarshalMermaid},
+	"plantuml": {".puml", core.MarshalPlantUML},
+}
+
+// diagrams returns class diagram formats to store. Without -diagram flag
+// -no_store_dot decides whether DOT diagram is stored.
+func diagrams() []string {
+	if formats := splitList(*diagram_f); len(formats) > 0 {
+		return formats
+	}
+
+	if !*no_store_dot_f {
+		return []string{"dot"}
+	}
+
+	return nil
+}
+
+// focusGraph returns part of dg around -focus type. Whole dg is returned
+// if no focus is set or focus type is not in dg.
+func focusGraph(dg graph.Graph) graph.Graph {
+	if *focus_f == "" {
+		return dg
+	}
+
+	tg, ok := dg.(*core.TypeGraph)
+	if !ok {
+		return dg
+	}
+
+	n, ok := tg.Find(*focus_f)
+	if !ok {
+		log.Printf("warning: focus type %s not found", *focus_f)
+		return dg
+	}
+
+	return tg.Focus(n.Type, *focus_depth_f)
+}
+
+// storeModel writes package model encoded by marshal to output with ext.
+// Model is taken right away, next package Generate may change it.
+func storeModel(g core.Generator, pkg *core.Package, ext string, marshal func(*core.Package) ([]byte, error), errs *RunErrors, wg *sync.WaitGroup) {
+	data, err := marshal(pkg)
+	if err != nil {
+		errs.Add(pkg.Pkg.PkgPath, g.Flag(), fmt.Errorf("%s model: %w", strings.TrimPrefix(ext, "."), err))
+		return
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		if err := writeOutput(OutputName(pkg, g, ext), data); err != nil {
+			errs.Add(pkg.Pkg.PkgPath, g.Flag(), fmt.Errorf("writing output: %w", err))
+		}
+	}()
+}
+
+// writeOutput writes data to fileName unless file already holds it.
+func writeOutput(fileName string, data []byte) error {
+	if existing, err := os.ReadFile(fileName); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+
+	log.Printf("Writing file %s", fileName)
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		return err
+	}
+	log.Printf("Done file %s", fileName)
+
+	return nil
+}
+
+// packageError returns error if package can not be inspected. Type errors
+// are ignored as they are expected while generated code is out of date.
+func packageError(pkg *packages.Package) error {
+	perrs := []error{}
+	for _, err := range pkg.Errors {
+		if err.Kind == packages.TypeError {
+			continue
+		}
+		perrs = append(perrs, err)
+	}
+
+	return errors.Join(perrs...)
+}
+
+// appIdentity returns hash identifying generator binary. Module versions are
+// used when known so the same generator built on another machine gives the
+// same identity; otherwise falls back to the executable content.
+func appIdentity() string {
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		parts := []string{bi.Main.Path, bi.Main.Version, bi.Main.Sum}
+		known := bi.Main.Version != "" && bi.Main.Version != "(devel)"
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				known = true
+				parts = append(parts, s.Value)
+			case "vcs.modified":
+				known = known && s.Value != "true"
+			}
+		}
+		for _, d := range bi.Deps {
+			parts = append(parts, d.Path, d.Version, d.Sum)
+		}
+
+		if known {
+			return core.HashStrings(parts...)
+		}
+	}
+
+	ex, err := os.Executable()
+	if err != nil {
+		panic(err)
+	}
+	data, err := os.ReadFile(ex)
+	if err != nil {
+		panic(err)
+	}
+	return core.HashBytes(data)
+}
+
+// withoutHash returns src with hash header line removed.
+func withoutHash(src []byte) []byte {
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	return bytes.Join(slices.DeleteFunc(lines, func(line []byte) bool {
+		return bytes.HasPrefix(line, []byte(hashPrefix))
+	}), nil)
+}
+
+// readOutputHash returns input hash stored in generated file header.
+func readOutputHash(fileName string) string {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if hash, ok := strings.CutPrefix(line, hashPrefix); ok {
+			return strings.TrimSpace(hash)
+		}
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+
+	return ""
 }
 
 func Inspect(pkgs map[string]*core.Package, generators ...core.Generator) {
@@ -300,7 +925,7 @@ func InspectCode(pkg *core.Package, node ast.Node, generators ...core.Generator)
 		return false
 	case *ast.FuncDecl:
 		for _, g := range generators {
-			g.NewFunc(nil, decl)
+			g.NewFunc(pkg, nil, decl)
 		}
 		return false
 	}
@@ -0,0 +1,345 @@
+package gogen
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/igadmg/gogen/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+type testGenerator struct {
+	core.GeneratorBaseT
+
+	code string // code Generate returns
+}
+
+func newTestGenerator(flag string) *testGenerator {
+	g := &testGenerator{
+		GeneratorBaseT: core.MakeGeneratorB(flag),
+	}
+	g.G = g
+	return g
+}
+
+func (g *testGenerator) Generate(pkg *core.Package) (bytes.Buffer, core.Diagnostics, error) {
+	var buf bytes.Buffer
+	buf.WriteString(g.code)
+	return buf, g.TakeDiagnostics(), nil
+}
+
+// setFlag sets gogen flag *f to v until test ends.
+func setFlag[T any](t *testing.T, f **T, v T) {
+	old := *f
+	t.Cleanup(func() { *f = old })
+	*f = &v
+}
+
+// writeModule writes files to a temporary example.com/game module and
+// returns its root. File names are slash separated and relative to root.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"go.mod": "module example.com/game\n\ngo 1.24\n"})
+	writeFiles(t, root, files)
+	return root
+}
+
+// writeFiles writes files below root, creating directories as needed.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for name, data := range files {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		require.NoError(t, os.WriteFile(name, []byte(data), 0644))
+	}
+}
+
+func TestWithoutHash(t *testing.T) {
+	a := []byte(hashPrefix + "a\n\npackage world\n")
+	b := []byte(hashPrefix + "b\n\npackage world\n")
+
+	assert.Equal(t, withoutHash(a), withoutHash(b))
+	assert.Equal(t, "\npackage world\n", string(withoutHash(a)))
+	assert.NotEqual(t, withoutHash(a), withoutHash([]byte(hashPrefix+"a\n\npackage game\n")))
+}
+
+func TestOutputHashSettings(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		ConfigFileName:             "settings:\n  ecs:\n    layer: LayerGame\n    verbose: true\n",
+		"world/" + ConfigFileName:  "settings:\n  ecs:\n    verbose: true\n    layer: LayerGame\n",
+		"render/" + ConfigFileName: "settings:\n  ecs:\n    layer: LayerRender\n",
+	})
+
+	old := projectConfigs
+	t.Cleanup(func() { projectConfigs = old })
+	projectConfigs = NewConfigs()
+
+	g := newTestGenerator("ecs")
+	hash := func(dir string) string {
+		return outputHash(g, &core.Package{Pkg: &packages.Package{Dir: filepath.Join(root, dir)}})
+	}
+
+	assert.Equal(t, hash("audio"), hash("world"), "same settings")
+	assert.NotEqual(t, hash("audio"), hash("render"), "changed setting")
+}
+
+func TestOutputHashFlags(t *testing.T) {
+	old := flagSet
+	t.Cleanup(func() { flagSet = old })
+
+	fg := flag.NewFlagSet("gogen", flag.ContinueOnError)
+	layer := fg.String("ecs.layer", "", "")
+	other := fg.String("gfx.layer", "", "")
+	flagSet = fg
+
+	g := newTestGenerator("ecs")
+	pkg := &core.Package{Pkg: &packages.Package{}}
+	hash := outputHash(g, pkg)
+
+	*other = "LayerRender"
+	assert.Equal(t, hash, outputHash(g, pkg), "other generator flag")
+
+	*layer = "LayerGame"
+	assert.NotEqual(t, hash, outputHash(g, pkg), "generator flag")
+}
+
+func TestGenerateSideOutputs(t *testing.T) {
+	dir := t.TempDir()
+	pkg := &core.Package{
+		Pkg:  &packages.Package{Dir: dir, PkgPath: "example.com/world"},
+		Name: "world",
+	}
+	g := newTestGenerator("ecs")
+
+	setFlag(t, &check_f, false)
+	setFlag(t, &diagram_f, "")
+	setFlag(t, &no_store_dot_f, true)
+	setFlag(t, &no_store_yaml_f, false)
+	setFlag(t, &no_store_json_f, true)
+
+	outputName := OutputName(pkg, g, ".go")
+	output := hashPrefix + outputHash(g, pkg) + "\n\npackage world\n"
+	require.NoError(t, os.WriteFile(outputName, []byte(output), 0644))
+
+	var wg sync.WaitGroup
+	errs := &RunErrors{}
+	generate(g, pkg, errs, &wg, nil)
+	wg.Wait()
+	require.NoError(t, errs.Err())
+
+	assert.FileExists(t, OutputName(pkg, g, ".yaml"), "side output of up to date package")
+	src, err := os.ReadFile(outputName)
+	require.NoError(t, err)
+	assert.Equal(t, output, string(src), "up to date output is kept")
+}
+
+func TestGenerateInvalidSource(t *testing.T) {
+	dir := t.TempDir()
+	pkg := &core.Package{
+		Pkg:  &packages.Package{Dir: dir, PkgPath: "example.com/world"},
+		Name: "world",
+	}
+	g := newTestGenerator("ecs")
+	g.code = "package world\n\nfunc {\n"
+
+	setFlag(t, &check_f, false)
+	setFlag(t, &diagram_f, "")
+	setFlag(t, &no_store_dot_f, true)
+	setFlag(t, &no_store_yaml_f, true)
+	setFlag(t, &no_store_json_f, true)
+
+	for range 2 {
+		var wg sync.WaitGroup
+		errs := &RunErrors{}
+		generate(g, pkg, errs, &wg, nil)
+		wg.Wait()
+		assert.Error(t, errs.Err(), "invalid output is reported every run")
+	}
+
+	outputName := OutputName(pkg, g, ".go")
+	src, err := os.ReadFile(outputName)
+	require.NoError(t, err)
+	assert.Contains(t, string(src), g.code)
+	assert.Empty(t, readOutputHash(outputName), "invalid output stays out of date")
+}
+
+func TestBuildFlags(t *testing.T) {
+	tests := []struct {
+		tags, flags, tag string
+		want             []string
+	}{
+		{"", "", "", []string{}},
+		{"", "", "pro", []string{"-tags=pro"}},
+		{"a,b", "", "", []string{"-tags=a,b"}},
+		{"a,,b", "-race  -v", "pro", []string{"-race", "-v", "-tags=a,b,pro"}},
+	}
+
+	for _, tt := range tests {
+		setFlag(t, &tags_f, tt.tags)
+		setFlag(t, &build_flags_f, tt.flags)
+		assert.Equal(t, tt.want, buildFlags(tt.tag), "tags %q, flags %q, tag %q", tt.tags, tt.flags, tt.tag)
+	}
+}
+
+func TestOutputName(t *testing.T) {
+	tests := []struct {
+		name     string
+		buildTag string
+		test     bool
+		flag     string
+		ext      string
+		want     string
+	}{
+		{"world", "", false, "ecs", ".go", "0.gen_ecs.go"},
+		{"world", "", false, "ECS", ".yaml", "0.gen_ecs.yaml"},
+		{"world", "pro", false, "ecs", ".go", "0.gen_ecs_pro.go"},
+		{"world", "", true, "ecs", ".go", "0.gen_ecs_test.go"},
+		{"world_test", "", true, "ecs", ".go", "0.gen_ecs_x_test.go"},
+		{"world", "pro", true, "ecs", ".go", "0.gen_ecs_pro_test.go"},
+	}
+
+	dir := filepath.Join("game", "world")
+	for _, tt := range tests {
+		pkg := &core.Package{
+			Pkg:      &packages.Package{Dir: dir},
+			Name:     tt.name,
+			BuildTag: tt.buildTag,
+			Test:     tt.test,
+		}
+		assert.Equal(t, filepath.Join(dir, tt.want), OutputName(pkg, newTestGenerator(tt.flag), tt.ext))
+	}
+}
+
+func TestLoadVariants(t *testing.T) {
+	const path = "github.com/igadmg/gogen/testdata/variants"
+
+	tests := []struct {
+		variants   string
+		want       []string // tags with differing packages
+		constraint string   // constraint of default build package
+	}{
+		{"", []string{}, ""},
+		{"lite", []string{}, ""},
+		{"pro", []string{"pro"}, "!pro"},
+		{"pro,lite", []string{"pro"}, "!pro"},
+	}
+
+	for _, tt := range tests {
+		setFlag(t, &tag_variants_f, tt.variants)
+
+		errs := &RunErrors{}
+		pkgNames := []string{"./testdata/variants"}
+		ppkgs := LoadPackages(pkgNames, "", errs)
+		variants := loadVariants(pkgNames, ppkgs, errs)
+		require.NoError(t, errs.Err())
+
+		tags := []string{}
+		for tag, vpkgs := range variants {
+			tags = append(tags, tag)
+			assert.Equal(t, tag, vpkgs[path].BuildTag)
+			assert.Equal(t, tag, vpkgs[path].Constraint)
+		}
+		slices.Sort(tags)
+		assert.Equal(t, tt.want, tags, tt.variants)
+		assert.Equal(t, tt.constraint, ppkgs[path].Constraint, tt.variants)
+	}
+}
+
+func TestLoadPackagesTests(t *testing.T) {
+	const path = "github.com/igadmg/gogen/testdata/tests"
+
+	fileNames := func(pkg *core.Package) []string {
+		names := []string{}
+		for _, f := range pkg.Files {
+			names = append(names, filepath.Base(pkg.Pkg.Fset.Position(f.File.Package).Filename))
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	tests := []struct {
+		tests bool
+		want  map[string][]string // files of every loaded package
+	}{
+		{false, map[string][]string{
+			path: {"tests.go"},
+		}},
+		{true, map[string][]string{
+			path:             {"tests.go"},
+			path + " [test]": {"tests_test.go"},
+			path + "_test":   {"x_test.go"},
+		}},
+	}
+
+	for _, tt := range tests {
+		setFlag(t, &tests_f, tt.tests)
+
+		errs := &RunErrors{}
+		ppkgs := LoadPackages([]string{"./testdata/tests"}, "", errs)
+		require.NoError(t, errs.Err())
+		require.Len(t, ppkgs, len(tt.want))
+
+		for key, files := range tt.want {
+			pkg, ok := ppkgs[key]
+			require.True(t, ok, key)
+			assert.Equal(t, files, fileNames(pkg), key)
+			assert.Equal(t, key != path, pkg.Test, key)
+		}
+	}
+}
+
+func TestCheckVariantTag(t *testing.T) {
+	tests := []struct {
+		tag string
+		ok  bool
+	}{
+		{"pro", true},
+		{"linux", false},
+		{"AMD64", false},
+		{"wasm", false},
+		{"test", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.ok, checkVariantTag(tt.tag) == nil, tt.tag)
+	}
+}
+
+func TestRemoveVariantOutputs(t *testing.T) {
+	dir := t.TempDir()
+	pkg := &core.Package{
+		Pkg:  &packages.Package{Dir: dir, PkgPath: "example.com/world"},
+		Name: "world",
+	}
+	variants := map[string]map[string]*core.Package{
+		"pro": {"example.com/world": {BuildTag: "pro"}},
+	}
+	for _, name := range []string{"0.gen_ecs_pro.go", "0.gen_ecs_lite.go"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+
+	setFlag(t, &tag_variants_f, "pro,lite")
+	ppkgs := map[string]*core.Package{"example.com/world": pkg}
+	g := newTestGenerator("ecs")
+
+	setFlag(t, &check_f, true)
+	stale := removeVariantOutputs(ppkgs, variants, &RunErrors{}, g)
+	assert.Equal(t, []string{filepath.Join(dir, "0.gen_ecs_lite.go")}, stale)
+	assert.FileExists(t, filepath.Join(dir, "0.gen_ecs_lite.go"))
+
+	setFlag(t, &check_f, false)
+	assert.Empty(t, removeVariantOutputs(ppkgs, variants, &RunErrors{}, g))
+	_, err := os.Stat(filepath.Join(dir, "0.gen_ecs_lite.go"))
+	assert.True(t, os.IsNotExist(err))
+	assert.FileExists(t, filepath.Join(dir, "0.gen_ecs_pro.go"))
+}
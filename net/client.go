@@ -0,0 +1,101 @@
+package net
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/igadmg/gogen/core"
+)
+
+// Client talks to a Service.
+type Client struct {
+	URL  string       // Service base URL, like http://localhost:7077.
+	HTTP *http.Client // http.DefaultClient if nil.
+}
+
+// Packages returns paths of packages loaded by service.
+func (c *Client) Packages(ctx context.Context) ([]string, error) {
+	var pkgs []string
+	err := c.getJSON(ctx, "/packages", nil, &pkgs)
+	return pkgs, err
+}
+
+// Model returns model of package pkg as seen by generator, empty generator
+// selects the only one service runs.
+func (c *Client) Model(ctx context.Context, generator, pkg string) (core.PackageDto, error) {
+	var model core.PackageDto
+	err := c.getJSON(ctx, "/model", url.Values{"generator": {generator}, "pkg": {pkg}}, &model)
+	return model, err
+}
+
+// Graph returns class diagram of generator in format. Diagram is focused
+// on type focus with neighborhood of depth unless focus is empty.
+func (c *Client) Graph(ctx context.Context, generator, format, focus string, depth int) ([]byte, error) {
+	q := url.Values{"generator": {generator}, "format": {format}}
+	if focus != "" {
+		q.Set("focus", focus)
+		q.Set("depth", strconv.Itoa(depth))
+	}
+
+	return c.do(ctx, http.MethodGet, "/graph", q)
+}
+
+// Generate asks service to reload package pkg and regenerate its outputs.
+func (c *Client) Generate(ctx context.Context, pkg string) (GenerateResult, error) {
+	var result GenerateResult
+	data, err := c.do(ctx, http.MethodPost, "/generate", url.Values{"pkg": {pkg}})
+	if err != nil {
+		return result, err
+	}
+
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, q url.Values, v any) error {
+	data, err := c.do(ctx, http.MethodGet, path, q)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, q url.Values) ([]byte, error) {
+	u := strings.TrimSuffix(c.URL, "/") + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	return data, nil
+}
@@ -5,11 +5,15 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"github.com/igadmg/goecs/ecs/net"
+	"github.com/igadmg/goex/gx"
+	"github.com/igadmg/goex/pprofex"
+	"github.com/igadmg/gogen"
 	"github.com/igadmg/gogen/core"
 )
 
@@ -22,7 +26,32 @@ func Usage() {
 	flag.PrintDefaults()
 }
 
+// Execute runs generators like gogen.Execute and keeps serving their model
+// with Service on -addr until interrupted. Service regenerates packages on
+// request, so -check and -watch modes are rejected. Build tag variants are
+// not supported either.
 func Execute(fg *flag.FlagSet, generators ...core.Generator) {
+	addr_f := fg.String("addr", "localhost:7077", "address model service listens on")
+	dir, generators := gogen.Setup(fg, os.Args[1:], generators...)
+
+	if boolFlag(fg, "schema") {
+		os.Stdout.Write(core.ModelSchema)
+		return
+	}
+
+	for _, name := range []string{"check", "watch"} {
+		if boolFlag(fg, name) {
+			log.Fatalf("-%s is not supported by model service", name)
+		}
+	}
+	if f := fg.Lookup("tag_variants"); f != nil && f.Value.String() != "" {
+		log.Fatal("-tag_variants is not supported by model service")
+	}
+
+	if boolFlag(fg, "profile") {
+		defer gx.Must(pprofex.WriteCPUProfile("gogen"))()
+	}
+
 	sigChan := make(chan os.Signal, 1)
 
 	// Регистрируем сигналы для Windows
@@ -32,15 +61,31 @@ func Execute(fg *flag.FlagSet, generators ...core.Generator) {
 		// syscall.SIGBREAK, // Ctrl+Break (раскомментировать если нужно)
 	)
 
-	ctx, cancel := context.WithCancel(context.Background())
-
-	s := net.Server{}
-	for _, g := range generators {
-		s.Register(g)
+	service, err := NewService(dir, generators...)
+	if err != nil {
+		log.Fatal(err)
 	}
-	go s.Listen(ctx)
+
+	hs := &http.Server{Addr: *addr_f, Handler: service}
+	go func() {
+		log.Printf("Serving model on %s", *addr_f)
+		if err := hs.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
 
 	sig := <-sigChan
 	log.Printf("Получен сигнал: %v. Завершение...", sig)
-	cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hs.Shutdown(ctx); err != nil {
+		log.Printf("Shutting down: %v", err)
+	}
+}
+
+// boolFlag reports whether boolean flag name registered by gogen.Setup is set.
+func boolFlag(fg *flag.FlagSet, name string) bool {
+	f := fg.Lookup(name)
+	return f != nil && f.Value.String() == "true"
 }
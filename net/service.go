@@ -0,0 +1,236 @@
+package net
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/igadmg/gogen"
+	"github.com/igadmg/gogen/core"
+	"gonum.org/v1/gonum/graph"
+)
+
+// Service serves inspected model of packages over HTTP.
+//
+//	GET  /packages                  paths of loaded packages
+//	GET  /model?pkg=P&generator=G   model of package P as seen by generator G, see core.ModelSchema
+//	GET  /graph?generator=G         class diagram, format=dot|mermaid|plantuml, focus=T and depth=N
+//	POST /generate?pkg=P            reload package P and regenerate its outputs
+//
+// Generator may be omitted when service runs only one. gogen.Setup must be
+// called before service is made. Build tag variants are not supported.
+type Service struct {
+	mu         sync.Mutex
+	generators []core.Generator
+	ppkgs      map[string]*core.Package
+	mux        *http.ServeMux
+}
+
+// GenerateResult is answer to generate request.
+type GenerateResult struct {
+	Changed bool     `json:"changed"` // package sources changed since last generation
+	Errors  []string `json:"errors,omitempty"`
+}
+
+var diagramFormats = map[string]func(graph.Graph) ([]byte, error){
+	"dot":      core.MarshalDot,
+	"mermaid":  core.MarshalMermaid,
+	"plantuml": core.MarshalPlantUML,
+}
+
+// NewService loads, inspects and generates packages matching pkgNames.
+func NewService(pkgNames []string, generators ...core.Generator) (*Service, error) {
+	for _, g := range generators {
+		if _, ok := g.(core.Invalidator); !ok {
+			return nil, fmt.Errorf("generator %s does not support reloading packages", g.Flag())
+		}
+	}
+
+	errs := &gogen.RunErrors{}
+	ppkgs := gogen.LoadPackages(pkgNames, "", errs)
+	if len(ppkgs) == 0 {
+		return nil, errors.Join(fmt.Errorf("no packages loaded"), errs.Err())
+	}
+
+	gogen.Inspect(ppkgs, generators...)
+	gogen.LinkPackages(ppkgs)
+	prepare(generators)
+	gogen.Generate(ppkgs, errs, generators...)
+	errs.Summary()
+
+	s := &Service{
+		generators: generators,
+		ppkgs:      ppkgs,
+		mux:        http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /packages", s.handlePackages)
+	s.mux.HandleFunc("GET /model", s.handleModel)
+	s.mux.HandleFunc("GET /graph", s.handleGraph)
+	s.mux.HandleFunc("POST /generate", s.handleGenerate)
+
+	return s, nil
+}
+
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Service) handlePackages(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, slices.Sorted(maps.Keys(s.ppkgs)))
+}
+
+func (s *Service) handleModel(w http.ResponseWriter, r *http.Request) {
+	g, err := s.generator(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pkg, err := s.pkg(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	data, err := g.Json(pkg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (s *Service) handleGraph(w http.ResponseWriter, r *http.Request) {
+	g, err := s.generator(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := r.FormValue("format")
+	if format == "" {
+		format = "dot"
+	}
+	marshal, ok := diagramFormats[format]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown diagram format %s", format), http.StatusBadRequest)
+		return
+	}
+
+	dg := g.Graph()
+	if focus := r.FormValue("focus"); focus != "" {
+		depth := 1
+		if d := r.FormValue("depth"); d != "" {
+			if depth, err = strconv.Atoi(d); err != nil {
+				http.Error(w, fmt.Sprintf("depth: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		tg, ok := dg.(*core.TypeGraph)
+		if !ok {
+			http.Error(w, fmt.Sprintf("generator %s graph can't be focused", g.Flag()), http.StatusBadRequest)
+			return
+		}
+		n, ok := tg.Find(focus)
+		if !ok {
+			http.Error(w, fmt.Sprintf("type %s not found", focus), http.StatusNotFound)
+			return
+		}
+		dg = tg.Focus(n.Type, depth)
+	}
+
+	data, err := marshal(dg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(data)
+}
+
+func (s *Service) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	pkg, err := s.pkg(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	dir := pkg.Pkg.Dir
+	errs := &gogen.RunErrors{}
+	changed := gogen.Reload(s.ppkgs, []string{dir}, errs, s.generators...)
+	if len(changed) > 0 {
+		prepare(s.generators)
+	}
+
+	// Reload inspects packages importing the changed one again, so model
+	// stays consistent. Only package and its tests are generated, importers
+	// are regenerated on their own request.
+	dpkgs := map[string]*core.Package{}
+	for path, p := range s.ppkgs {
+		if p.Pkg.Dir == dir {
+			dpkgs[path] = p
+		}
+	}
+	gogen.Generate(dpkgs, errs, s.generators...)
+
+	result := GenerateResult{Changed: len(changed) > 0}
+	for _, err := range errs.Errors() {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	writeJSON(w, result)
+}
+
+// prepare prepares model of generators. Generate does not run for packages
+// with up to date outputs, so model served must not depend on it.
+func prepare(generators []core.Generator) {
+	for _, g := range generators {
+		g.Prepare()
+	}
+}
+
+// pkg returns package request is about.
+func (s *Service) pkg(r *http.Request) (*core.Package, error) {
+	pkg, ok := s.ppkgs[r.FormValue("pkg")]
+	if !ok {
+		return nil, fmt.Errorf("package %s not loaded", r.FormValue("pkg"))
+	}
+
+	return pkg, nil
+}
+
+// generator returns generator request is about.
+func (s *Service) generator(r *http.Request) (core.Generator, error) {
+	flag := r.FormValue("generator")
+	if flag == "" {
+		if len(s.generators) != 1 {
+			return nil, fmt.Errorf("generator is required")
+		}
+		return s.generators[0], nil
+	}
+
+	for _, g := range s.generators {
+		if g.Flag() == flag {
+			return g, nil
+		}
+	}
+
+	return nil, fmt.Errorf("generator %s not found", flag)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,131 @@
+package net
+
+import (
+	"context"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/igadmg/gogen"
+	"github.com/igadmg/gogen/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// modelType returns type of model by name.
+func modelType(t *testing.T, model core.PackageDto, name string) core.TypeDto {
+	t.Helper()
+
+	for _, tt := range model.Types {
+		if tt.Name == name {
+			return tt
+		}
+	}
+
+	require.Fail(t, "type not found", name)
+	return core.TypeDto{}
+}
+
+func TestService(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("testdata", "world", "world.go"))
+	require.NoError(t, err)
+
+	// Fixture is copied to own module, so test can edit it.
+	root := t.TempDir()
+	write := func(name, data string) {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(data), 0644))
+	}
+	write("go.mod", "module example.com/world\n\ngo 1.24\n")
+	write("world.go", string(src))
+	t.Chdir(root)
+
+	templates := fstest.MapFS{
+		"world.tmpl": {Data: []byte(`package {{.Pkg.Name}}
+{{range .Types}}
+func (t {{localTypeName .}}) TypeName() string { return "{{.GetName}}" }
+{{end}}`)},
+	}
+	g, err := core.NewTemplateGenerator("world", templates, []string{"*.tmpl"})
+	require.NoError(t, err)
+
+	dir, generators := gogen.Setup(flag.NewFlagSet("gogen", flag.ContinueOnError), []string{"-world", "."}, g)
+	output := filepath.Join(root, "0.gen_world.go")
+
+	service, err := NewService(dir, generators...)
+	require.NoError(t, err)
+	require.FileExists(t, output)
+
+	srv := httptest.NewServer(service)
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := &Client{URL: srv.URL}
+
+	pkgs, err := c.Packages(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"example.com/world"}, pkgs)
+
+	model, err := c.Model(ctx, "", pkgs[0])
+	require.NoError(t, err)
+	assert.Equal(t, core.ModelVersion, model.Version)
+	require.Len(t, model.Types, 3)
+	player := modelType(t, model, "Player")
+	assert.Equal(t, "world.Base", player.Bases[0].Type)
+	require.Len(t, player.Funcs, 1)
+	assert.Equal(t, "(p *Player)", player.Funcs[0].Receiver)
+
+	mmd, err := c.Graph(ctx, "world", "mermaid", "Pos", 1)
+	require.NoError(t, err)
+	assert.Contains(t, string(mmd), "example_com_world_Player --> example_com_world_Pos : Pos")
+	assert.NotContains(t, string(mmd), "example_com_world_Base")
+
+	_, err = c.Model(ctx, "", "example.com/missing")
+	assert.ErrorContains(t, err, "404")
+
+	result, err := c.Generate(ctx, pkgs[0])
+	require.NoError(t, err)
+	assert.False(t, result.Changed)
+	assert.Empty(t, result.Errors)
+
+	write("world.go", string(src)+"\ntype Vel struct{ X, Y float32 }\n")
+	result, err = c.Generate(ctx, pkgs[0])
+	require.NoError(t, err)
+	assert.True(t, result.Changed)
+	assert.Empty(t, result.Errors)
+
+	code, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, string(code), `func (t Vel) TypeName() string { return "Vel" }`)
+
+	model, err = c.Model(ctx, "", pkgs[0])
+	require.NoError(t, err)
+	assert.Len(t, model.Types, 4)
+	modelType(t, model, "Vel")
+	player = modelType(t, model, "Player")
+	assert.Equal(t, "world.Base", player.Bases[0].Type, "model is prepared again after reload")
+	require.Len(t, player.Funcs, 1)
+
+	// Outputs are up to date now, model of new service is still prepared.
+	g, err = core.NewTemplateGenerator("world", templates, []string{"*.tmpl"})
+	require.NoError(t, err)
+	service, err = NewService(dir, g)
+	require.NoError(t, err)
+
+	srv2 := httptest.NewServer(service)
+	defer srv2.Close()
+	c = &Client{URL: srv2.URL}
+
+	model, err = c.Model(ctx, "", pkgs[0])
+	require.NoError(t, err)
+	player = modelType(t, model, "Player")
+	assert.Equal(t, "world.Base", player.Bases[0].Type)
+	require.Len(t, player.Funcs, 1)
+	assert.Equal(t, "(p *Player)", player.Funcs[0].Receiver)
+
+	mmd, err = c.Graph(ctx, "world", "mermaid", "", 0)
+	require.NoError(t, err)
+	assert.Contains(t, string(mmd), "example_com_world_Player --> example_com_world_Pos : Pos")
+}
@@ -0,0 +1,16 @@
+package world
+
+type Base struct{ ID int }
+
+type Pos struct{ X, Y float32 }
+
+type Player struct {
+	Base
+	Pos  Pos
+	Name string
+}
+
+func (p *Player) Move(dx, dy float32) {
+	p.Pos.X += dx
+	p.Pos.Y += dy
+}
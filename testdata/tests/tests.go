@@ -0,0 +1,5 @@
+package tests
+
+type Player struct {
+	Name string
+}
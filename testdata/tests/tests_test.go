@@ -0,0 +1,5 @@
+package tests
+
+type playerFixture struct {
+	Player Player
+}
@@ -0,0 +1,7 @@
+package tests_test
+
+import "github.com/igadmg/gogen/testdata/tests"
+
+type PlayerCase struct {
+	Player tests.Player
+}
@@ -0,0 +1,3 @@
+package variants
+
+type Player struct{ Name string }
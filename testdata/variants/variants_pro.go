@@ -0,0 +1,5 @@
+//go:build pro
+
+package variants
+
+type Stats struct{ Score int }
@@ -0,0 +1,302 @@
+package gogen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/igadmg/gogen/core"
+)
+
+// Watch runs generators once and then keeps polling package directories.
+// Packages whose sources changed are loaded and inspected again, outputs of
+// all packages are regenerated if their input hash changed. Directories
+// below patterns ending with /... are polled too, so packages added later
+// are picked up. Build tag variants are not supported. Never returns unless
+// generators can't be reinspected.
+func Watch(pkgNames []string, interval time.Duration, generators ...core.Generator) error {
+	if tag_variants_f != nil && len(splitList(*tag_variants_f)) > 0 {
+		return fmt.Errorf("-tag_variants is not supported in watch mode")
+	}
+
+	for _, g := range generators {
+		if _, ok := g.(core.Invalidator); !ok {
+			return fmt.Errorf("generator %s does not support watch mode", g.Flag())
+		}
+	}
+
+	errs := &RunErrors{}
+	ppkgs := LoadPackages(pkgNames, "", errs)
+	Inspect(ppkgs, generators...)
+	LinkPackages(ppkgs)
+	Generate(ppkgs, errs, generators...)
+	errs.Summary()
+
+	dirs := watchDirs(pkgNames, ppkgs)
+	log.Printf("Watching %d directories", len(dirs))
+
+	for range time.Tick(interval) {
+		ndirs := watchDirs(pkgNames, ppkgs)
+		changed := changedDirs(dirs, ndirs)
+		dirs = ndirs
+		if len(changed) == 0 {
+			continue
+		}
+
+		errs := &RunErrors{}
+		if len(Reload(ppkgs, changed, errs, generators...)) == 0 {
+			errs.Summary()
+			continue
+		}
+
+		Generate(ppkgs, errs, generators...)
+		errs.Summary()
+	}
+
+	return nil
+}
+
+// Reload loads packages in dirs again and replaces ones whose sources
+// changed in ppkgs. Packages of dirs which are not loaded anymore, like test
+// package of a dir without _test.go files, are removed with their outputs.
+// Packages which failed to load are kept. Generators forget old packages
+// and inspect new ones. Packages importing
+// changed or removed ones refer to their old types, so they are inspected
+// again too. Imports of all packages are linked again. Every generator must
+// be core.Invalidator. Returns changed packages, removed ones included.
+func Reload(ppkgs map[string]*core.Package, dirs []string, errs *RunErrors, generators ...core.Generator) map[string]*core.Package {
+	invalidate := func(pkg *core.Package) {
+		for _, g := range generators {
+			g.(core.Invalidator).Invalidate(pkg)
+		}
+	}
+
+	load := []string{}
+	gone := map[string]bool{}
+	for _, dir := range dirs {
+		if dirState(dir) == "" {
+			gone[dir] = true
+		} else {
+			load = append(load, dir)
+		}
+	}
+
+	cpkgs := map[string]*core.Package{}
+	failed := map[string]bool{}
+	if len(load) > 0 {
+		n := errs.Len()
+		cpkgs = LoadPackages(load, "", errs)
+		if cpkgs == nil {
+			// Nothing loaded, keep packages as they were.
+			for _, dir := range load {
+				failed[dir] = true
+			}
+		}
+		for _, err := range errs.Errors()[n:] {
+			var rerr *RunError
+			if errors.As(err, &rerr) {
+				failed[rerr.Pkg] = true
+			}
+		}
+	}
+
+	loaded := map[string]bool{}
+	for _, dir := range load {
+		loaded[dir] = true
+	}
+
+	changed := map[string]*core.Package{}
+	for path, pkg := range ppkgs {
+		if _, ok := cpkgs[path]; ok || failed[pkg.Pkg.Dir] || failed[pkg.Pkg.PkgPath] {
+			continue
+		}
+		if !gone[pkg.Pkg.Dir] && !loaded[pkg.Pkg.Dir] {
+			continue
+		}
+
+		log.Printf("Removed package %s", path)
+		invalidate(pkg)
+		removeOutputs(pkg, errs, generators...)
+		delete(ppkgs, path)
+		changed[path] = pkg
+	}
+
+	for path, pkg := range cpkgs {
+		if old, ok := ppkgs[path]; ok {
+			if old.Hash == pkg.Hash {
+				delete(cpkgs, path)
+				continue
+			}
+
+			invalidate(old)
+		}
+
+		log.Printf("Changed package %s", path)
+		ppkgs[path] = pkg
+		changed[path] = pkg
+	}
+	if len(changed) == 0 {
+		return changed
+	}
+
+	inspect := maps.Clone(cpkgs)
+	for path, pkg := range importers(ppkgs, changed) {
+		invalidate(pkg)
+		inspect[path] = pkg
+	}
+
+	Inspect(inspect, generators...)
+	LinkPackages(ppkgs)
+	return changed
+}
+
+// removeOutputs removes outputs generators wrote for pkg.
+func removeOutputs(pkg *core.Package, errs *RunErrors, generators ...core.Generator) {
+	exts := []string{".go", ".yaml", ".json"}
+	for _, df := range diagramFormats {
+		exts = append(exts, df.ext)
+	}
+
+	for _, g := range generators {
+		for _, ext := range exts {
+			name := OutputName(pkg, g, ext)
+			if _, err := os.Stat(name); err != nil {
+				continue
+			}
+
+			log.Printf("Removing file %s", name)
+			if err := os.Remove(name); err != nil {
+				errs.Add(pkg.Pkg.PkgPath, g.Flag(), err)
+			}
+		}
+	}
+}
+
+// importers returns packages of ppkgs which import any of pkgs, directly or
+// through other packages. Packages of pkgs are not returned.
+func importers(ppkgs, pkgs map[string]*core.Package) map[string]*core.Package {
+	found := map[string]*core.Package{}
+	queue := []string{}
+	for _, pkg := range pkgs {
+		queue = append(queue, pkg.Pkg.PkgPath)
+	}
+
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+
+		for ipath, ipkg := range ppkgs {
+			if _, ok := pkgs[ipath]; ok {
+				continue
+			}
+			if _, ok := found[ipath]; ok {
+				continue
+			}
+
+			if _, ok := ipkg.ImportsByPath[path]; ok {
+				found[ipath] = ipkg
+				queue = append(queue, ipkg.Pkg.PkgPath)
+			}
+		}
+	}
+
+	return found
+}
+
+// watchDirs returns state of every package directory and of every
+// directory below pkgNames patterns ending with /... which go tool would
+// match.
+func watchDirs(pkgNames []string, ppkgs map[string]*core.Package) map[string]string {
+	dirs := map[string]string{}
+	for _, pkg := range ppkgs {
+		dirs[pkg.Pkg.Dir] = dirState(pkg.Pkg.Dir)
+	}
+
+	for _, name := range pkgNames {
+		root, ok := strings.CutSuffix(filepath.ToSlash(name), "/...")
+		if !ok || !(filepath.IsAbs(root) || strings.HasPrefix(root, ".")) {
+			continue
+		}
+		root, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+
+		filepath.WalkDir(root, func(dir string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if dir != root {
+				if n := d.Name(); strings.HasPrefix(n, ".") || strings.HasPrefix(n, "_") || n == "testdata" || n == "vendor" {
+					return filepath.SkipDir
+				}
+				if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+					return filepath.SkipDir // other module
+				}
+			}
+
+			dirs[dir] = dirState(dir)
+			return nil
+		})
+	}
+
+	return dirs
+}
+
+// changedDirs returns directories which state differs in dirs and ndirs,
+// added and removed ones included.
+func changedDirs(dirs, ndirs map[string]string) []string {
+	changed := []string{}
+	for dir, state := range ndirs {
+		if old, ok := dirs[dir]; !ok || old != state {
+			changed = append(changed, dir)
+		}
+	}
+	for dir := range dirs {
+		if _, ok := ndirs[dir]; !ok {
+			changed = append(changed, dir)
+		}
+	}
+
+	slices.Sort(changed)
+	return changed
+}
+
+// dirState returns names, sizes and modification times of Go sources in dir.
+// Generated 0.gen_* files are ignored, so writing outputs does not trigger
+// another run. State is only used to notice a change, package content hash
+// decides whether anything has to be regenerated. Empty for directory
+// without Go sources.
+func dirState(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	state := map[string]string{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || filepath.Ext(name) != ".go" || strings.HasPrefix(name, "0.gen_") {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		state[name] = fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano())
+	}
+
+	var sb strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(state)) {
+		fmt.Fprintf(&sb, "%s=%s;", name, state[name])
+	}
+	return sb.String()
+}
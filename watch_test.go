@@ -0,0 +1,113 @@
+package gogen
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/igadmg/gogen/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"a/a.go": "package a\n\ntype A struct{ X int }\n",
+		"b/b.go": "package b\n\nimport \"example.com/game/a\"\n\ntype B struct{ A a.A }\n",
+	})
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Chdir(root)
+
+	g, err := core.NewTemplateGenerator("fields", fstest.MapFS{
+		"fields.tmpl": {Data: []byte(`package {{.Pkg.Name}}
+{{range .Fields}}{{with .GetType}}
+// {{.GetFullName}}{{range fields .}} {{.GetName}}{{end}}{{end}}{{end}}
+`)},
+	}, []string{"*.tmpl"})
+	require.NoError(t, err)
+
+	pkgNames, generators := Setup(flag.NewFlagSet("gogen", flag.ContinueOnError), []string{"-fields", "./..."}, g)
+
+	errs := &RunErrors{}
+	ppkgs := LoadPackages(pkgNames, "", errs)
+	Inspect(ppkgs, generators...)
+	LinkPackages(ppkgs)
+	Generate(ppkgs, errs, generators...)
+	require.NoError(t, errs.Err())
+	assert.Contains(t, read("b/0.gen_fields.go"), "// a.A X\n")
+
+	dirs := watchDirs(pkgNames, ppkgs)
+	writeFiles(t, root, map[string]string{
+		"a/a.go": "package a\n\ntype A struct{ X, Y int }\n",
+		"c/c.go": "package c\n\nimport \"example.com/game/a\"\n\ntype C struct{ A *a.A }\n",
+	})
+
+	changed := changedDirs(dirs, watchDirs(pkgNames, ppkgs))
+	assert.Equal(t, []string{filepath.Join(root, "a"), filepath.Join(root, "c")}, changed)
+
+	cpkgs := Reload(ppkgs, changed, errs, generators...)
+	assert.Len(t, cpkgs, 2)
+	Generate(ppkgs, errs, generators...)
+	require.NoError(t, errs.Err())
+
+	assert.Contains(t, read("b/0.gen_fields.go"), "// a.A X Y\n", "importer is inspected again")
+	assert.Contains(t, read("c/0.gen_fields.go"), "// a.A X Y\n", "new package is picked up")
+
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "c")))
+	cpkgs = Reload(ppkgs, []string{filepath.Join(root, "c")}, errs, generators...)
+	assert.Len(t, cpkgs, 1)
+	_, ok := ppkgs["example.com/game/c"]
+	assert.False(t, ok, "removed package is dropped")
+}
+
+func TestReloadRemovedTests(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"a/a.go":      "package a\n\ntype A struct{ X int }\n",
+		"a/a_test.go": "package a\n\ntype fixture struct{ A A }\n",
+	})
+	t.Chdir(root)
+
+	g, err := core.NewTemplateGenerator("fields", fstest.MapFS{
+		"fields.tmpl": {Data: []byte(`package {{.Pkg.Name}}
+{{range .Types}}
+// {{.GetName}}{{end}}
+`)},
+	}, []string{"*.tmpl"})
+	require.NoError(t, err)
+
+	pkgNames, generators := Setup(flag.NewFlagSet("gogen", flag.ContinueOnError), []string{"-fields", "-tests", "./..."}, g)
+
+	errs := &RunErrors{}
+	ppkgs := LoadPackages(pkgNames, "", errs)
+	Inspect(ppkgs, generators...)
+	LinkPackages(ppkgs)
+	Generate(ppkgs, errs, generators...)
+	require.NoError(t, errs.Err())
+	require.FileExists(t, filepath.Join(root, "a", "0.gen_fields_test.go"))
+
+	require.NoError(t, os.Remove(filepath.Join(root, "a", "a_test.go")))
+	cpkgs := Reload(ppkgs, []string{filepath.Join(root, "a")}, errs, generators...)
+	require.NoError(t, errs.Err())
+	assert.Len(t, cpkgs, 1)
+
+	_, ok := ppkgs["example.com/game/a [test]"]
+	assert.False(t, ok, "test package is dropped")
+	_, err = os.Stat(filepath.Join(root, "a", "0.gen_fields_test.go"))
+	assert.True(t, os.IsNotExist(err), "test output is removed")
+	assert.FileExists(t, filepath.Join(root, "a", "0.gen_fields.go"))
+}
+
+func TestWatchTagVariants(t *testing.T) {
+	setFlag(t, &tag_variants_f, "pro")
+
+	g := newTestGenerator("ecs")
+	assert.Error(t, Watch([]string{"./testdata/variants"}, time.Second, g))
+}